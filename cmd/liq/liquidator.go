@@ -0,0 +1,408 @@
+package main
+
+import (
+	"errors"
+	"jito-bot/pkg/fixed"
+	"jito-bot/pkg/jito"
+	mev "jito-bot/pkg/jito/gen"
+	"jito-bot/pkg/marginfi"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	lookup "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	budget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+const (
+	liquidationCooldown      = 10 * time.Second
+	liquidationComputeLimit  = 1_400_000
+	liquidationComputePrice  = 10_000
+	createAtaIdempotentIxTag = 1
+	accountReconcileInterval = 5 * time.Minute
+)
+
+var ErrNothingToWithdraw = errors.New("seized collateral rounds down to zero")
+
+var (
+	minLiabilityValue = fixed.MustI80F48FromFloat64(10)
+
+	state          marginfiState
+	marginfiClient *marginfi.Client
+	accountIndex   = marginfi.NewAccountIndex(marginfi.GroupAddress)
+
+	// liquidationMu guards the cooldown of targets shared by the scanner and the mempool watcher
+	liquidationMu      sync.Mutex
+	recentLiquidations = make(map[solana.PublicKey]time.Time)
+
+	lookupTablesMu    sync.Mutex
+	lookupTablesCache = make(map[solana.PublicKey]solana.PublicKeySlice)
+)
+
+// marginfiState is a snapshot of banks and prices shared by the scanner and the mempool watcher,
+// accounts live in accountIndex which is kept current by its own subscription
+type marginfiState struct {
+	sync.RWMutex
+	banks        marginfi.BankMap
+	oraclePrices marginfi.OraclePriceMap
+}
+
+// refresh takes the latest banks and prices of marginfiClient, which refreshes itself in the background
+func (s *marginfiState) refresh() error {
+	banks, oraclePrices := marginfiClient.Snapshot()
+	if len(oraclePrices) < len(banks) {
+		slog.Warn("banks without valid oracle price are skipped", "banks", len(banks)-len(oraclePrices))
+	}
+	// share values on chain lag behind by the interest accrued since bank's last update
+	banks = banks.WithAccruedInterest(time.Now().Unix())
+
+	if err := pythPrices.load(banks); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.banks = banks
+	s.oraclePrices = oraclePrices
+	accountIndex.SetBanks(banks, oraclePrices)
+	return nil
+}
+
+// snapshot maps are replaced on refresh and never modified, they can be used after the lock is released
+func (s *marginfiState) snapshot() (marginfi.BankMap, marginfi.OraclePriceMap) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.banks, s.oraclePrices
+}
+
+// setPrice reprices banks of the oracle in the state and in accountIndex, prices map is copied so earlier snapshots
+// stay intact. Banks for which the price is older than their oracle max age keep the last price.
+// Accounts whose health was recomputed are returned with the new snapshot
+func (s *marginfiState) setPrice(oracle solana.PublicKey, oraclePrice *marginfi.OraclePrice) (marginfi.BankMap, marginfi.OraclePriceMap, []solana.PublicKey) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now().Unix()
+	oracleBanks := make([]solana.PublicKey, 0, 1)
+	for _, bankPK := range s.banks.ByOracle(oracle) {
+		if !oraclePrice.IsStale(s.banks[bankPK], now) {
+			oracleBanks = append(oracleBanks, bankPK)
+		}
+	}
+	if len(oracleBanks) == 0 {
+		return s.banks, s.oraclePrices, nil
+	}
+	changed := make(marginfi.OraclePriceMap, len(oracleBanks))
+	oraclePrices := make(marginfi.OraclePriceMap, len(s.oraclePrices)+len(oracleBanks))
+	for bankPK, price := range s.oraclePrices {
+		oraclePrices[bankPK] = price
+	}
+	for _, bankPK := range oracleBanks {
+		changed[bankPK] = oraclePrice
+		oraclePrices[bankPK] = oraclePrice
+	}
+	s.oraclePrices = oraclePrices
+	return s.banks, oraclePrices, accountIndex.SetPrices(changed)
+}
+
+// syncAccountIndex keeps accountIndex subscribed to account changes, reconnecting when the stream drops
+func syncAccountIndex() {
+	for {
+		wsClient, err := ws.Connect(ctx, rpcWsUrl)
+		if err != nil {
+			slog.Error("unable to connect to rpc websocket", "err", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		err = accountIndex.Subscribe(ctx, wsClient)
+		wsClient.Close()
+		slog.Error("account subscription dropped", "err", err)
+		// updates could be missed while reconnecting
+		if err := accountIndex.Reconcile(ctx, solanaConnection); err != nil {
+			slog.Error("unable to reconcile accounts", "err", err)
+		}
+	}
+}
+
+func reconcileAccountIndex() {
+	for {
+		time.Sleep(accountReconcileInterval)
+		if err := accountIndex.Reconcile(ctx, solanaConnection); err != nil {
+			slog.Error("unable to reconcile accounts", "err", err)
+		}
+	}
+}
+
+// scanAndLiquidate refreshes banks and prices and sends a liquidation bundle for each profitable target
+func scanAndLiquidate(route SwapRoute) error {
+	if err := state.refresh(); err != nil {
+		return err
+	}
+
+	banks, oraclePrices := state.snapshot()
+
+	now := time.Now()
+	for _, accountPK := range accountIndex.Liquidatable() {
+		if accountPK == liquidatorAccountPK {
+			continue
+		}
+		account, ok := accountIndex.Get(accountPK)
+		if !ok {
+			continue
+		}
+		plan, ok := findLiquidation(accountPK, account, banks, oraclePrices)
+		if !ok {
+			continue
+		}
+		tryLiquidate(banks, accountPK, account, plan, route)
+	}
+
+	slog.Info("scan took", "duration", time.Since(now), "accounts", accountIndex.Len())
+	return nil
+}
+
+func findLiquidation(
+	accountPK solana.PublicKey, account *marginfi.MarginfiAccount, banks marginfi.BankMap, oraclePrices marginfi.OraclePriceMap,
+) (*marginfi.LiquidationPlan, bool) {
+	assets, liabilities := account.ComputeHealthComponents(banks, oraclePrices, marginfi.MarginRequirementTypeMaintenance)
+	if !assets.LessThan(liabilities) || !liabilities.BiggerThanOrEqual(minLiabilityValue) {
+		return nil, false
+	}
+	plan, ok := account.FindBestLiquidation(banks, oraclePrices, marginfi.LiquidationSolverOptions{
+		SafetyMargin: marginfi.DefaultLiquidationSafetyMargin,
+	})
+	if !ok || plan.ProfitUsd < minProfitUsd {
+		return nil, false
+	}
+	slog.Info("Account can be liquidated",
+		"account", accountPK.String(),
+		"owner", account.Authority.String(),
+		"assets", assets.AsFloat64(),
+		"liabilities", liabilities.AsFloat64(),
+		"assetBank", plan.AssetBankPK.String(),
+		"liabilityBank", plan.LiabilityBankPK.String(),
+		"assetAmount", plan.AssetAmount,
+		"profitUsd", plan.ProfitUsd,
+		"healthBefore", plan.HealthBefore,
+		"healthAfter", plan.HealthAfter)
+	return plan, true
+}
+
+// claimLiquidation is false while the target cools down after the last attempt, expired attempts are pruned
+func claimLiquidation(targetPK solana.PublicKey, now time.Time) bool {
+	liquidationMu.Lock()
+	defer liquidationMu.Unlock()
+
+	for accountPK, lastAttempt := range recentLiquidations {
+		if now.Sub(lastAttempt) >= liquidationCooldown {
+			delete(recentLiquidations, accountPK)
+		}
+	}
+	if _, ok := recentLiquidations[targetPK]; ok {
+		return false
+	}
+	recentLiquidations[targetPK] = now
+	return true
+}
+
+// tryLiquidate sends liquidation bundle, backrunTxs go first in the bundle.
+// Banks must be a snapshot, quote and rpc calls are made without holding any lock
+func tryLiquidate(
+	banks marginfi.BankMap,
+	targetPK solana.PublicKey,
+	target *marginfi.MarginfiAccount,
+	plan *marginfi.LiquidationPlan,
+	route SwapRoute,
+	backrunTxs ...*solana.Transaction,
+) {
+	if !claimLiquidation(targetPK, time.Now()) {
+		return
+	}
+
+	liquidatorAccount, ok := accountIndex.Get(liquidatorAccountPK)
+	if !ok {
+		slog.Error("liquidator account not found", "account", liquidatorAccountPK.String())
+		return
+	}
+
+	tx, err := makeLiquidationTx(banks, liquidatorAccount, targetPK, target, plan, route)
+	if err != nil {
+		slog.Error("unable to make liquidation tx", "account", targetPK.String(), "err", err)
+		return
+	}
+	if err := sendBundle(append(backrunTxs, tx)...); err != nil {
+		slog.Error("unable to send liquidation bundle", "account", targetPK.String(), "err", err)
+	}
+}
+
+// makeLiquidationTx wraps liquidation into a marginfi flashloan, liquidator takes over the debt, withdraws
+// seized collateral, swaps it to the liability token and repays, health is checked only at the end of the flashloan.
+// Whatever is left after repaying stays in wallet's liability token account as profit.
+func makeLiquidationTx(
+	banks marginfi.BankMap,
+	liquidatorAccount *marginfi.MarginfiAccount,
+	targetPK solana.PublicKey,
+	target *marginfi.MarginfiAccount,
+	plan *marginfi.LiquidationPlan,
+	route SwapRoute,
+) (*solana.Transaction, error) {
+	assetBank, liabilityBank := banks[plan.AssetBankPK], banks[plan.LiabilityBankPK]
+	owner := wallet.PublicKey()
+
+	assetAta, _, err := solana.FindAssociatedTokenAddress(owner, assetBank.Mint)
+	if err != nil {
+		return nil, err
+	}
+	liabilityAta, _, err := solana.FindAssociatedTokenAddress(owner, liabilityBank.Mint)
+	if err != nil {
+		return nil, err
+	}
+
+	liquidateIx, err := marginfi.MakeLiquidateIx(banks, liquidatorAccountPK, liquidatorAccount, targetPK, target, plan.AssetBankPK, plan.LiabilityBankPK, plan.AssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	// only the seized collateral is withdrawn, a deposit liquidator already had in the bank stays
+	withdrawAmount, err := withdrawableAmount(assetBank, plan.AssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	withdrawIx, err := marginfi.MakeWithdrawIx(liquidatorAccountPK, liquidatorAccount, plan.AssetBankPK, assetBank, assetAta, withdrawAmount, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	var swapIxs []solana.Instruction
+	var lookupTables []solana.PublicKey
+	if assetBank.Mint != liabilityBank.Mint {
+		swapIxs, lookupTables, err = route.MakeSwapIxs(owner, assetBank.Mint, liabilityBank.Mint, withdrawAmount, plan.LiabilityAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	repayIx := marginfi.MakeRepayIx(liquidatorAccountPK, liquidatorAccount, plan.LiabilityBankPK, liabilityBank, liabilityAta, 0, true)
+
+	// repay all closes the liability, asset balance keeps rounding dust and any earlier deposit
+	endObservationAccounts, err := marginfi.ObservationAccounts(banks, liquidatorAccount.WithoutBanks(plan.LiabilityBankPK), plan.AssetBankPK)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := make([]solana.Instruction, 0, 10+len(swapIxs))
+	instructions = append(instructions,
+		budget.NewSetComputeUnitLimitInstruction(liquidationComputeLimit).Build(),
+		budget.NewSetComputeUnitPriceInstruction(liquidationComputePrice).Build(),
+		makeCreateAtaIdempotentIx(owner, owner, assetBank.Mint),
+		makeCreateAtaIdempotentIx(owner, owner, liabilityBank.Mint),
+	)
+	startFlashloanIndex := len(instructions)
+	instructions = append(instructions, nil, liquidateIx, withdrawIx)
+	instructions = append(instructions, swapIxs...)
+	instructions = append(instructions, repayIx)
+	endFlashloanIndex := len(instructions)
+	instructions = append(instructions, marginfi.MakeEndFlashloanIx(liquidatorAccountPK, liquidatorAccount, endObservationAccounts))
+	instructions[startFlashloanIndex] = marginfi.MakeStartFlashloanIx(liquidatorAccountPK, liquidatorAccount, uint64(endFlashloanIndex))
+	instructions = append(instructions, jito.MakeTipIx(owner))
+
+	tables, err := loadLookupTables(lookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	blockhash, err := solanaConnection.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := solana.NewTransaction(instructions, blockhash.Value.Blockhash, solana.TransactionPayer(owner), solana.TransactionAddressTables(tables))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Sign(walletSigner); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// withdrawableAmount is the asset amount credited to liquidator, shares are rounded down on deposit
+// so converting them back can give one less than amount
+func withdrawableAmount(bank *marginfi.Bank, amount uint64) (uint64, error) {
+	shares := bank.GetAssetShares(fixed.I80F48FromUint64(amount))
+	withdrawable, err := bank.GetAssetQuantity(shares).Uint64()
+	if err != nil {
+		return 0, err
+	}
+	if withdrawable == 0 {
+		return 0, ErrNothingToWithdraw
+	}
+	return withdrawable, nil
+}
+
+func sendBundle(txs ...*solana.Transaction) error {
+	bundle, err := jito.MakeBundle(txs...)
+	if err != nil {
+		return err
+	}
+	res, err := searcher.SendBundle(ctx, &mev.SendBundleRequest{
+		Bundle: bundle,
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("bundle sent", "UUID", res.Uuid)
+	return nil
+}
+
+func loadLookupTables(tableKeys []solana.PublicKey) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice, len(tableKeys))
+	missing := make([]solana.PublicKey, 0, len(tableKeys))
+	lookupTablesMu.Lock()
+	for _, tableKey := range tableKeys {
+		if addresses, ok := lookupTablesCache[tableKey]; ok {
+			tables[tableKey] = addresses
+			continue
+		}
+		missing = append(missing, tableKey)
+	}
+	lookupTablesMu.Unlock()
+	if len(missing) == 0 {
+		return tables, nil
+	}
+
+	res, err := solanaConnection.GetMultipleAccounts(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+	for i, acc := range res.Value {
+		if acc == nil {
+			return nil, errors.New("lookup table not found")
+		}
+		table, err := lookup.DecodeAddressLookupTableState(acc.Data.GetBinary())
+		if err != nil {
+			return nil, err
+		}
+		tables[missing[i]] = table.Addresses
+	}
+
+	lookupTablesMu.Lock()
+	for tableKey, addresses := range tables {
+		lookupTablesCache[tableKey] = addresses
+	}
+	lookupTablesMu.Unlock()
+	return tables, nil
+}
+
+func makeCreateAtaIdempotentIx(payer solana.PublicKey, owner solana.PublicKey, mint solana.PublicKey) solana.Instruction {
+	ata, _, _ := solana.FindAssociatedTokenAddress(owner, mint)
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(payer, true, true),
+		solana.NewAccountMeta(ata, true, false),
+		solana.NewAccountMeta(owner, false, false),
+		solana.NewAccountMeta(mint, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+	}
+	return solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, accounts, []byte{createAtaIdempotentIxTag})
+}
@@ -2,21 +2,19 @@ package main
 
 import (
 	"context"
-	"jito-bot/pkg/fixed"
+	"flag"
 	"jito-bot/pkg/jito"
 	mev "jito-bot/pkg/jito/gen"
+	"jito-bot/pkg/jupiter"
 	"jito-bot/pkg/marginfi"
-	"jito-bot/pkg/pyth"
 	"log"
 	"log/slog"
 	"os"
 	"strconv"
 	"time"
 
-	"github.com/davecgh/go-spew/spew"
 	_ "github.com/joho/godotenv/autoload"
 
-	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/redis/go-redis/v9"
@@ -28,6 +26,7 @@ var (
 	ctx = context.Background()
 
 	solanaConnection *rpc.Client
+	rpcWsUrl         string
 	rdb              = redis.NewClient(&redis.Options{})
 )
 
@@ -40,6 +39,16 @@ var (
 var (
 	wallet              solana.PrivateKey
 	tradeAmountLamports uint64
+	liquidatorAccountPK solana.PublicKey
+	minProfitUsd        float64 = 1
+	jupiterApiUrl               = jupiter.DefaultUrl
+)
+
+const (
+	scanInterval        = 10 * time.Second
+	bankRefreshInterval = 10 * time.Second
+	swapSlippageBps     = 50
+	swapMaxAccounts     = 24
 )
 
 func init() {
@@ -48,6 +57,7 @@ func init() {
 	log.SetFlags(log.LUTC | log.Ldate | log.Ltime | log.Lmicroseconds)
 
 	solanaConnection = rpc.New(os.Getenv("RPC_URL"))
+	rpcWsUrl = os.Getenv("RPC_WS_URL")
 
 	jitoAuthKey = solana.MustPrivateKeyFromBase58(os.Getenv("JITO_AUTH_PRIVATE_KEY"))
 	blockEngineUrl = os.Getenv("JITO_BLOCK_ENGINE_URL")
@@ -57,9 +67,37 @@ func init() {
 	if err != nil {
 		log.Fatal("Error parsing TRADER_TRADE_AMOUNT_LAMPORTS", err)
 	}
+	if liquidatorAccount := os.Getenv("MARGINFI_LIQUIDATOR_ACCOUNT"); liquidatorAccount != "" {
+		liquidatorAccountPK = solana.MustPublicKeyFromBase58(liquidatorAccount)
+	}
+	if minProfit := os.Getenv("MIN_PROFIT_USD"); minProfit != "" {
+		minProfitUsd, err = strconv.ParseFloat(minProfit, 64)
+		if err != nil {
+			log.Fatal("Error parsing MIN_PROFIT_USD", err)
+		}
+	}
+	if url := os.Getenv("JUPITER_API_URL"); url != "" {
+		jupiterApiUrl = url
+	}
 }
 
 func main() {
+	createAccount := flag.Bool("create-account", false, "create a marginfi account owned by the wallet to liquidate from and exit")
+	flag.Parse()
+
+	if *createAccount {
+		accountPK, sig, err := marginfi.CreateLiquidatorAccount(solanaConnection, wallet)
+		if err != nil {
+			log.Fatalf("unable to create liquidator account: %v", err)
+		}
+		slog.Info("liquidator account created, set MARGINFI_LIQUIDATOR_ACCOUNT and restart", "account", accountPK.String(), "sig", sig.String())
+		return
+	}
+	// creating the account costs rent, it is never done implicitly
+	if liquidatorAccountPK.IsZero() {
+		log.Fatal("MARGINFI_LIQUIDATOR_ACCOUNT is not set, run with -create-account to create one")
+	}
+
 	slog.Info("starting",
 		"wallet", wallet.PublicKey().String(),
 		"tradeAmountLamports", tradeAmountLamports,
@@ -84,232 +122,36 @@ func main() {
 
 	searcher = mev.NewSearcherServiceClient(conn)
 
-	marginfiClient, err := marginfi.NewClient(solanaConnection)
-	if err != nil {
-		log.Fatalf("unable to create marginfi client: %v", err)
+	route := &jupiterSwapRoute{
+		client:      &jupiter.JupiterClient{Url: jupiterApiUrl},
+		slippageBps: swapSlippageBps,
+		maxAccounts: swapMaxAccounts,
 	}
-
-	gpa, err := solanaConnection.GetProgramAccountsWithOpts(ctx, solana.MustPublicKeyFromBase58("MFv2hWf31Z9kbCa1snEPYctwafyhdvnV7FZnsebVacA"), &rpc.GetProgramAccountsOpts{
-		Filters: []rpc.RPCFilter{{
-			Memcmp: &rpc.RPCFilterMemcmp{
-				Offset: 8,
-				Bytes:  marginfi.GroupAddress.Bytes(),
-			},
-		}}})
-	//{
-	//	Memcmp: &rpc.RPCFilterMemcmp{
-	//		Offset: 8 + 32,
-	//		Bytes:  solana.MustPublicKeyFromBase58("2Hog8LpazH8kebxwvfWXRPdVu52pM2MGWHQbGVRzRsjp").Bytes(),
-	//	},
-	//}},
-
-	tenBucks := fixed.MustI80F48FromFloat64(10)
-
-	parsedAccounts := make([]*marginfi.MarginfiAccount, len(gpa))
-	for i, gpaAcc := range gpa {
-		parsedAccounts[i] = marginfi.ParseMarginfiAccount(gpaAcc.Account.Data.GetBinary())
-	}
-
-	now := time.Now()
-	for _, account := range parsedAccounts {
-		//account := marginfi.ParseMarginfiAccount(gpaAcc.Account.Data.GetBinary())
-		canBeLiquidated, assets, liabilities := account.CanBeLiquidated(marginfiClient)
-		if canBeLiquidated && liabilities.BiggerThanOrEqual(tenBucks) {
-			maxLiabilityPaydown := assets.AsFloat64() - liabilities.AsFloat64()
-			_ = maxLiabilityPaydown
-			slog.Info("Account can be liquidated", "owner", account.Authority.String(), "health", maxLiabilityPaydown, "assets", assets.AsFloat64(), "liabilities", liabilities.AsFloat64())
-		}
+	marginfiClient, err = marginfi.NewClient(solanaConnection)
+	if err != nil {
+		log.Fatalf("unable to load marginfi banks: %v", err)
 	}
-
-	slog.Info("took", time.Since(now))
-
-	//
-	//spew.Dump(canBeLiquidated)
-
-	//myAcc, err := solanaConnection.GetAccountInfo(ctx, solana.MustPublicKeyFromBase58("AbZoHRCWAV2RzWNTxssYZutmrQX7GMdc4RpTWZ8gtT6n"))
-	//if err != nil {
-	//	log.Fatalf("unable to get account info: %v", err)
-	//}
-	//
-	//mAcc := marginfi.ParseMarginfiAccount(myAcc.Bytes())
-	//spew.Dump(mAcc)
-	//
-	//bankAcc, err := solanaConnection.GetAccountInfo(ctx, solana.MustPublicKeyFromBase58("CCKtUs6Cgwo4aaQUmBPmyoApH2gUDErxNZCAntD6LYGh"))
-	//if err != nil {
-	//	log.Fatalf("unable to get account info: %v", err)
-	//}
-	//
-	//bank := marginfi.ParseBank(bankAcc.Bytes())
-	//spew.Dump(bank)
-	//
-	//myBalance := mAcc.LendingAccount.Balances[0]
-	//
-	//spew.Dump(bank.GetAssetQuantity(myBalance.AssetShares).AsFloat64())
-
-	// balanceMy := fixed.I80F48{U128: num.MustU128FromString("287207082485166")}
-	// spew.Dump(balanceMy.Float64())
-
-	// bInt := big.Int{}
-
-	// bankAssetShareValue := uint256.MustFromDecimal("287207082485166")
-	// spew.Dump(bankAssetShareValue)
-	// MULTIPLIER := uint256.MustFromDecimal("281474976710656")
-	// rem := uint256.Int{}
-	// div, _ := bankAssetShareValue.DivMod(bankAssetShareValue, MULTIPLIER, &rem)
-
-	// res := div.Float64()
-	// res += rem.Float64() / 281474976710656
-
-	// spew.Dump(res)
-
-	// spew.Dump(bankAssetShareValue.Lsh(bankAssetShareValue, 48).String())
-
-	// balance := num.MustU128FromString("287207082485166")
-	// MULTIPLIER := num.MustU128FromString("281474976710656")
-	// div, mod := balance.QuoRem(MULTIPLIER)
-
-	// res := div.AsFloat64()
-	// res += mod.AsFloat64() / 281474976710656
-	// spew.Dump(res)
-	// balance.QuoRem()
-	// mul := balance.Mul(bankBalance).AsBigFloat()
-	// spew.Dump(new(big.Float).Quo(mul, big.NewFloat(2e48)).String())
-	// spew.Dump(new(big.Int).Quo(mul, big.NewInt(0).set))
-
-	// zero := uint64(0)
-	// gpa, err := solanaConnection.GetProgramAccountsWithOpts(ctx, solana.MustPublicKeyFromBase58("MFv2hWf31Z9kbCa1snEPYctwafyhdvnV7FZnsebVacA"), &rpc.GetProgramAccountsOpts{
-	// 	Filters: []rpc.RPCFilter{{
-	// 		Memcmp: &rpc.RPCFilterMemcmp{
-	// 			Offset: 8,
-	// 			Bytes:  solana.MustPublicKeyFromBase58("4qp6Fx6tnZkY5Wropq9wUYgtFxXKwE6viZxFHg3rdAG8").Bytes(),
-	// 		},
-	// 	}},
-	// 	DataSlice: &rpc.DataSlice{
-	// 		Offset: &zero,
-	// 		Length: &zero,
-	// 	},
-	// })
-	// if err != nil {
-	// 	log.Fatalf("unable to get program accounts: %v", err)
-	// }
-
-	// fmt.Println(len(gpa))
-
-	return
-
-	// pks := make([]string, len(gpa))
-	// for i := 0; i < len(pks); i++ {
-	// 	pks[i] = gpa[i].Pubkey.String()
-	// }
-
-	// accsChunk, err := solanaConnection.GetMultipleAccounts(ctx, pks...)
-	// if err != nil {
-	// 	log.Fatalf("unable to get multiple accounts: %v", err)
-	// }
-
-	// fmt.Println(accsChunk)
-
-	// bundleResSub, err := searcher.SubscribeBundleResults(ctx, &mev.SubscribeBundleResultsRequest{})
-	// if err != nil {
-	// 	log.Fatalf("unable to subscribe: %v", err)
-	// }
-	// go func() {
-	// 	for {
-	// 		bundleRes, err := bundleResSub.Recv()
-	// 		if err != nil {
-	// 			log.Fatalf("unable to receive: %v", err)
-	// 		}
-	// 		slog.Info("bundle result:", "bundle", bundleRes)
-	// 	}
-	// }()
-
-	// fmt.Println("subscribing...", len(pks))
-
-	mempoolSub, err := searcher.SubscribeMempool(ctx, &mev.MempoolSubscription{
-		// Regions: []string{"frankfurt", "amsterdam", "ny", "tokyo"},
-		// Msg: &mev.MempoolSubscription_WlaV0Sub{
-		// 	WlaV0Sub: &mev.WriteLockedAccountSubscriptionV0{
-		// 		Accounts: []string{"H6ARHf6YXhGYeQfUzQNGk6rDNnLBQKrenN712K4AQJEG"},
-		// 	},
-		// },
-		Msg: &mev.MempoolSubscription_ProgramV0Sub{
-			ProgramV0Sub: &mev.ProgramSubscriptionV0{
-				Programs: []string{"FsJ3A3u2vn5cTVofAjvy6y5kwABJAqYWpe4975bi2epH"},
-			},
-		},
+	go marginfiClient.RefreshPeriodically(ctx, bankRefreshInterval, func(err error) {
+		slog.Error("marginfi refresh failed", "err", err)
 	})
-	if err != nil {
-		log.Fatalf("unable to subscribe: %v", err)
+	if err := accountIndex.Reconcile(ctx, solanaConnection); err != nil {
+		log.Fatalf("unable to load marginfi accounts: %v", err)
 	}
-
-	for {
-		notif, err := mempoolSub.Recv()
-		if err != nil {
-			log.Fatalf("unable to receive: %v", err)
-		}
-		for _, msg := range notif.Transactions {
-			tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(msg.Data))
-			if err != nil {
-				log.Fatalf("unable to decode transaction: %v", err)
-			}
-			// fmt.Println(tx.String())
-			for _, ix := range tx.Message.Instructions {
-				if !pyth.IsUpdatePriceInstruction(ix.Data) {
-					continue
-				}
-				priceAccount, err := tx.Message.Account(ix.Accounts[1])
-				if err != nil {
-					panic(err)
-				}
-				if priceAccount != solana.MustPublicKeyFromBase58("H6ARHf6YXhGYeQfUzQNGk6rDNnLBQKrenN712K4AQJEG") {
-					continue
-				}
-				spew.Dump(pyth.ParseUpdatePriceInstruction(ix.Data))
-
-				// 	programId, err := tx.Message.Program(ix.ProgramIDIndex)
-				// 	if err != nil {
-				// 		log.Fatalf("unable to get program id: %v", err)
-				// 	}
-				// 	if programId != TCOMP_PROGRAM_ADDRESS {
-				// 		continue
-				// 	}
-
-				// 	ixDataDecoder := bin.NewBinDecoder(ix.Data)
-				// 	anchorHeader, err := ixDataDecoder.ReadNBytes(8)
-				// 	if err != nil {
-				// 		log.Fatalf("unable to read ixType: %v", err)
-				// 	}
-
-				// 	isListTx := hex.EncodeToString(anchorHeader) == "36aec14311298426"
-
-				// 	if !isListTx {
-				// 		break
-				// 	}
-				// 	slog.Info(tx.String())
-
-				// isPoolCreate := ixType == 0
-				// if !isPoolCreate {
-				// 	break
-				// }
-				// fmt.Println("got pool create instruction")
-				// fmt.Println(tx.String())
-				// if !isPoolCreate {
-				// 	break
-				// }
-				// ixDataDecoder.SkipBytes(1) // skip nonce
-
-				// openTimeRaw, err := ixDataDecoder.ReadUint64(binary.LittleEndian)
-				// if err != nil {
-				// 	log.Fatalf("unable to read openTime: %v", err)
-				// }
-				// openTime := int64(openTimeRaw)
-
-				// slog.Info("create pool tx", "serverTime", notif.ServerSideTs.AsTime(), "expiration", notif.ExpirationTime.AsTime(), "poolOpenTime", time.Unix(openTime, 0).UTC())
-				// if openTime > time.Now().Unix() {
-				// 	break
-				// }
+	go syncAccountIndex()
+	go syncPythPrices(route)
+	go reconcileAccountIndex()
+
+	go func() {
+		for {
+			if err := scanAndLiquidate(route); err != nil {
+				slog.Error("scan failed", "err", err)
 			}
+			time.Sleep(scanInterval)
 		}
+	}()
+
+	if err := watchPythUpdates(route); err != nil {
+		log.Fatalf("pyth mempool watcher failed: %v", err)
 	}
 }
 
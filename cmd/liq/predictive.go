@@ -0,0 +1,161 @@
+package main
+
+import (
+	mev "jito-bot/pkg/jito/gen"
+	"jito-bot/pkg/marginfi"
+	"jito-bot/pkg/pyth"
+	"log/slog"
+	"math"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// pendingPriceUpdate is the price oracle account will hold once the pending transaction lands,
+// for legacy pyth accounts it's the predicted aggregate, not a single publisher quote
+type pendingPriceUpdate struct {
+	price    int64
+	conf     uint64
+	exponent int32
+}
+
+// watchPythUpdates listens for pending pyth publisher and pull oracle transactions and backruns the ones that make
+// marginfi accounts liquidatable, the oracle update goes first in the bundle so liquidation lands on the new price
+func watchPythUpdates(route SwapRoute) error {
+	mempoolSub, err := searcher.SubscribeMempool(ctx, &mev.MempoolSubscription{
+		Msg: &mev.MempoolSubscription_ProgramV0Sub{
+			ProgramV0Sub: &mev.ProgramSubscriptionV0{
+				Programs: []string{
+					pyth.ProgramAddress.String(),
+					pyth.ReceiverProgramAddress.String(),
+					pyth.PushOracleProgramAddress.String(),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		notif, err := mempoolSub.Recv()
+		if err != nil {
+			return err
+		}
+		for _, msg := range notif.Transactions {
+			tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(msg.Data))
+			if err != nil {
+				slog.Error("unable to decode transaction", "err", err)
+				continue
+			}
+			updates := findPendingPriceUpdates(tx)
+			if len(updates) == 0 {
+				continue
+			}
+			handlePendingPriceUpdates(tx, updates, route)
+		}
+	}
+}
+
+func findPendingPriceUpdates(tx *solana.Transaction) map[solana.PublicKey]pendingPriceUpdate {
+	updates := make(map[solana.PublicKey]pendingPriceUpdate)
+	quotes := make(map[solana.PublicKey][]*pyth.UpdPriceInstruction)
+	for _, ix := range tx.Message.Instructions {
+		programId, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil {
+			continue
+		}
+		if pyth.IsPostUpdateInstruction(programId, ix.Data) {
+			position := pyth.PostUpdatePriceAccountPosition(programId)
+			if len(ix.Accounts) <= position {
+				continue
+			}
+			priceAccount, err := tx.Message.Account(ix.Accounts[position])
+			if err != nil {
+				continue
+			}
+			message, err := pyth.ParsePostUpdateInstruction(ix.Data)
+			if err != nil {
+				continue
+			}
+			updates[priceAccount] = pendingPriceUpdate{price: message.Price, conf: message.Conf, exponent: message.Exponent}
+			continue
+		}
+		if programId != pyth.ProgramAddress {
+			continue
+		}
+		if !pyth.IsUpdatePriceInstruction(ix.Data) {
+			continue
+		}
+		accounts, err := instructionAccounts(tx, ix)
+		if err != nil {
+			continue
+		}
+		quote, err := pyth.ParseUpdatePriceInstruction(accounts, ix.Data)
+		if err != nil {
+			continue
+		}
+		quotes[quote.PriceAccount] = append(quotes[quote.PriceAccount], quote)
+	}
+
+	for priceAccount, priceQuotes := range quotes {
+		aggregate, exponent, ok := pythPrices.predict(priceAccount, priceQuotes)
+		if !ok {
+			continue
+		}
+		updates[priceAccount] = pendingPriceUpdate{price: aggregate.PriceComponent, conf: aggregate.ConfComponent, exponent: exponent}
+	}
+	return updates
+}
+
+func instructionAccounts(tx *solana.Transaction, ix solana.CompiledInstruction) ([]solana.PublicKey, error) {
+	accounts := make([]solana.PublicKey, 0, len(ix.Accounts))
+	for _, index := range ix.Accounts {
+		account, err := tx.Message.Account(index)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// handlePendingPriceUpdates re-evaluates only accounts holding banks priced by the updated oracles
+func handlePendingPriceUpdates(oracleTx *solana.Transaction, updates map[solana.PublicKey]pendingPriceUpdate, route SwapRoute) {
+	banks, oraclePrices := state.snapshot()
+	if banks == nil {
+		return
+	}
+
+	affectedBanks := make([]solana.PublicKey, 0)
+	for oracle, update := range updates {
+		oracleBanks := banks.ByOracle(oracle)
+		if len(oracleBanks) == 0 {
+			continue
+		}
+		scale := math.Pow10(int(update.exponent))
+		price, conf := float64(update.price)*scale, float64(update.conf)*scale
+		// program would refuse the price as well, no point in backrunning it
+		if err := pyth.ValidateConfidence(price, conf, marginfi.OracleValidation.MaxConfRatio); err != nil {
+			continue
+		}
+		oraclePrices = oraclePrices.WithRealtimePrice(oracleBanks, price, conf)
+		affectedBanks = append(affectedBanks, oracleBanks...)
+	}
+
+	for _, accountPK := range accountIndex.AccountsByBank(affectedBanks...) {
+		if accountPK == liquidatorAccountPK {
+			continue
+		}
+		account, ok := accountIndex.Get(accountPK)
+		if !ok {
+			continue
+		}
+		plan, ok := findLiquidation(accountPK, account, banks, oraclePrices)
+		if !ok {
+			continue
+		}
+		slog.Info("backrunning pyth update", "account", accountPK.String(), "oracleTx", oracleTx.Signatures[0].String())
+		tryLiquidate(banks, accountPK, account, plan, route, oracleTx)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"jito-bot/pkg/marginfi"
+	"jito-bot/pkg/pyth"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+var (
+	pythPrices = newPythPriceCache()
+
+	errOraclesChanged = errors.New("pyth oracles changed")
+)
+
+// pythPriceCache keeps legacy pyth price accounts of the banks current together with publisher quotes
+// seen in the mempool that haven't landed yet, so the next aggregate can be predicted
+type pythPriceCache struct {
+	sync.RWMutex
+	prices  map[solana.PublicKey]*pyth.PriceData
+	slots   map[solana.PublicKey]uint64
+	pending map[solana.PublicKey][]*pyth.UpdPriceInstruction
+}
+
+func newPythPriceCache() *pythPriceCache {
+	return &pythPriceCache{
+		prices:  make(map[solana.PublicKey]*pyth.PriceData),
+		slots:   make(map[solana.PublicKey]uint64),
+		pending: make(map[solana.PublicKey][]*pyth.UpdPriceInstruction),
+	}
+}
+
+func pythOracles(banks marginfi.BankMap) []solana.PublicKey {
+	seen := make(map[solana.PublicKey]struct{})
+	oracles := make([]solana.PublicKey, 0)
+	for _, bank := range banks {
+		if bank.Config.OracleSetup != marginfi.OracleSetupPyth {
+			continue
+		}
+		oracle := bank.Config.OracleKeys[0]
+		if _, ok := seen[oracle]; ok {
+			continue
+		}
+		seen[oracle] = struct{}{}
+		oracles = append(oracles, oracle)
+	}
+	return oracles
+}
+
+// load fetches price accounts of pyth banks, websocket updates keep them current in between
+func (c *pythPriceCache) load(banks marginfi.BankMap) error {
+	oracles := pythOracles(banks)
+	if len(oracles) == 0 {
+		return nil
+	}
+	res, err := solanaConnection.GetMultipleAccountsWithOpts(ctx, oracles, &rpc.GetMultipleAccountsOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return err
+	}
+	for i, acc := range res.Value {
+		if acc == nil {
+			continue
+		}
+		c.update(oracles[i], acc.Data.GetBinary(), res.Context.Slot)
+	}
+	return nil
+}
+
+// update stores the price account unless a newer one is known and drops pending quotes that landed or expired,
+// false when the account was ignored
+func (c *pythPriceCache) update(oracle solana.PublicKey, data []byte, slot uint64) bool {
+	priceData, err := pyth.ParsePriceData(data)
+	if err != nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if slot < c.slots[oracle] {
+		return false
+	}
+	c.prices[oracle] = priceData
+	c.slots[oracle] = slot
+
+	pending := c.pending[oracle][:0]
+	for _, quote := range c.pending[oracle] {
+		component := priceData.ComponentByPublisher(quote.Publisher)
+		if component == nil || quote.PublishSlot <= component.Latest.PublishSlot || quote.PublishSlot+pyth.MaxSlotDifference < slot {
+			continue
+		}
+		pending = append(pending, quote)
+	}
+	c.pending[oracle] = pending
+	return true
+}
+
+// predict merges the quotes with pending ones seen before and returns the aggregate of the slot following the newest quote,
+// the quotes are kept as pending until the price account shows them landed
+func (c *pythPriceCache) predict(oracle solana.PublicKey, quotes []*pyth.UpdPriceInstruction) (pyth.PriceInfo, int32, bool) {
+	c.Lock()
+	defer c.Unlock()
+	priceData, ok := c.prices[oracle]
+	if !ok {
+		return pyth.PriceInfo{}, 0, false
+	}
+	c.pending[oracle] = append(c.pending[oracle], quotes...)
+
+	slot := c.slots[oracle]
+	for _, quote := range c.pending[oracle] {
+		slot = max(slot, quote.PublishSlot)
+	}
+	// quotes are applied in the order they were seen, a publisher's newer quote replaces the older one
+	aggregate, _ := priceData.PredictAggregate(oracle, slot+1, c.pending[oracle]...)
+	if aggregate.Status != pyth.PriceStatusTrading {
+		return pyth.PriceInfo{}, 0, false
+	}
+	return aggregate, priceData.Exponent, true
+}
+
+// syncPythPrices subscribes to price accounts of pyth banks, reconnecting when the stream drops
+// or the set of oracles changes. Every landed price re-evaluates accounts holding the repriced banks
+func syncPythPrices(route SwapRoute) {
+	for {
+		banks, _ := state.snapshot()
+		oracles := pythOracles(banks)
+		if len(oracles) == 0 {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		wsClient, err := ws.Connect(ctx, rpcWsUrl)
+		if err != nil {
+			slog.Error("unable to connect to rpc websocket", "err", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		err = subscribePythPrices(wsClient, oracles, route)
+		wsClient.Close()
+		slog.Error("pyth price subscription dropped", "err", err)
+	}
+}
+
+func subscribePythPrices(wsClient *ws.Client, oracles []solana.PublicKey, route SwapRoute) error {
+	errs := make(chan error, len(oracles))
+	for _, oracle := range oracles {
+		sub, err := wsClient.AccountSubscribeWithOpts(oracle, rpc.CommitmentConfirmed, solana.EncodingBase64)
+		if err != nil {
+			return err
+		}
+		go func(oracle solana.PublicKey, sub *ws.AccountSubscription) {
+			defer sub.Unsubscribe()
+			for {
+				res, err := sub.Recv()
+				if err != nil {
+					errs <- err
+					return
+				}
+				data := res.Value.Data.GetBinary()
+				if pythPrices.update(oracle, data, res.Context.Slot) {
+					applyPythPrice(oracle, data, res.Context.Slot, route)
+				}
+			}
+		}(oracle, sub)
+	}
+
+	ticker := time.NewTicker(scanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case <-ticker.C:
+			banks, _ := state.snapshot()
+			current := pythOracles(banks)
+			if !sameOracles(oracles, current) {
+				return errOraclesChanged
+			}
+		}
+	}
+}
+
+// applyPythPrice reprices banks of the oracle, only health of accounts holding them is recomputed
+// and those that fell below maintenance are liquidated
+func applyPythPrice(oracle solana.PublicKey, data []byte, slot uint64, route SwapRoute) {
+	oraclePrice, err := marginfi.ParseOraclePrice(marginfi.OracleSetupPyth, data, slot)
+	if err != nil {
+		// invalid prices are refused by the program as well, the last valid one stays
+		return
+	}
+	banks, oraclePrices, affected := state.setPrice(oracle, oraclePrice)
+
+	for _, accountPK := range affected {
+		if accountPK == liquidatorAccountPK {
+			continue
+		}
+		if health, ok := accountIndex.Health(accountPK); !ok || !health.IsLiquidatable() {
+			continue
+		}
+		account, ok := accountIndex.Get(accountPK)
+		if !ok {
+			continue
+		}
+		plan, ok := findLiquidation(accountPK, account, banks, oraclePrices)
+		if !ok {
+			continue
+		}
+		tryLiquidate(banks, accountPK, account, plan, route)
+	}
+}
+
+func sameOracles(a, b []solana.PublicKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	known := make(map[solana.PublicKey]struct{}, len(a))
+	for _, oracle := range a {
+		known[oracle] = struct{}{}
+	}
+	for _, oracle := range b {
+		if _, ok := known[oracle]; !ok {
+			return false
+		}
+	}
+	return true
+}
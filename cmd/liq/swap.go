@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"jito-bot/pkg/jupiter"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrSwapOutputTooLow = errors.New("swap output doesn't cover liability")
+
+// SwapRoute swaps seized collateral back to the liability token, instructions move amountIn from owner's
+// inputMint ATA to its outputMint ATA and must fail if less than minAmountOut is received
+type SwapRoute interface {
+	MakeSwapIxs(owner solana.PublicKey, inputMint solana.PublicKey, outputMint solana.PublicKey, amountIn uint64, minAmountOut uint64) (ixs []solana.Instruction, lookupTables []solana.PublicKey, err error)
+}
+
+type jupiterSwapRoute struct {
+	client      *jupiter.JupiterClient
+	slippageBps uint16
+	maxAccounts int
+}
+
+func (r *jupiterSwapRoute) MakeSwapIxs(owner solana.PublicKey, inputMint solana.PublicKey, outputMint solana.PublicKey, amountIn uint64, minAmountOut uint64) ([]solana.Instruction, []solana.PublicKey, error) {
+	quote, err := r.client.GetQuote(inputMint, outputMint, amountIn, r.slippageBps, r.maxAccounts)
+	if err != nil {
+		return nil, nil, err
+	}
+	// swap reverts below the threshold, not below out amount
+	if quote.OtherAmountThreshold < minAmountOut {
+		return nil, nil, ErrSwapOutputTooLow
+	}
+
+	swapIxs, err := r.client.GetSwapInstructions(quote, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ixs := make([]solana.Instruction, 0, len(swapIxs.SetupInstructions)+2)
+	ixs = append(ixs, swapIxs.SetupInstructions...)
+	ixs = append(ixs, swapIxs.SwapInstruction)
+	if swapIxs.CleanupInstruction != nil {
+		ixs = append(ixs, swapIxs.CleanupInstruction)
+	}
+	return ixs, swapIxs.AddressLookupTables, nil
+}
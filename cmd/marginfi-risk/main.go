@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"jito-bot/pkg/marginfi"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	ctx = context.Background()
+
+	solanaConnection *rpc.Client
+)
+
+func init() {
+	log.SetFlags(log.LUTC | log.Ldate | log.Ltime | log.Lmicroseconds)
+
+	solanaConnection = rpc.New(os.Getenv("RPC_URL"))
+}
+
+func main() {
+	address := flag.String("address", "", "marginfi account or authority address")
+	jsonOutput := flag.Bool("json", false, "print reports as JSON instead of a table")
+	metadataCache := flag.String("metadata-cache", "mrgn-bank-metadata-cache.json", "path of the bank metadata cache")
+	flag.Parse()
+
+	if *address == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	addressPK, err := solana.PublicKeyFromBase58(*address)
+	if err != nil {
+		log.Fatalf("invalid address: %v", err)
+	}
+
+	marginfiClient, err := marginfi.NewClient(solanaConnection)
+	if err != nil {
+		log.Fatalf("unable to load marginfi banks: %v", err)
+	}
+	if err := marginfiClient.LoadMetadata(*metadataCache); err != nil {
+		log.Printf("bank metadata unavailable, mints are shown instead of symbols: %v", err)
+	}
+	banks, oraclePrices := marginfiClient.Snapshot()
+	metadata := marginfiClient.Metadata()
+	banks = banks.WithAccruedInterest(time.Now().Unix())
+
+	accounts, err := loadAccounts(addressPK)
+	if err != nil {
+		log.Fatalf("unable to load marginfi accounts: %v", err)
+	}
+	if len(accounts) == 0 {
+		log.Fatalf("no marginfi accounts found for %s", addressPK)
+	}
+
+	accountPKs := make([]solana.PublicKey, 0, len(accounts))
+	for accountPK := range accounts {
+		accountPKs = append(accountPKs, accountPK)
+	}
+	sort.Slice(accountPKs, func(i, j int) bool { return bytes.Compare(accountPKs[i][:], accountPKs[j][:]) < 0 })
+
+	reports := make([]*marginfi.AccountReport, 0, len(accounts))
+	for _, accountPK := range accountPKs {
+		report, err := accounts[accountPK].Report(accountPK, banks, oraclePrices, metadata)
+		if err != nil {
+			log.Fatalf("unable to build report for %s: %v", accountPK, err)
+		}
+		reports = append(reports, report)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	for _, report := range reports {
+		printReport(report)
+	}
+}
+
+// loadAccounts treats address as marginfi account first and falls back to authority lookup
+func loadAccounts(address solana.PublicKey) (map[solana.PublicKey]*marginfi.MarginfiAccount, error) {
+	res, err := solanaConnection.GetAccountInfo(ctx, address)
+	if err != nil && err != rpc.ErrNotFound {
+		return nil, err
+	}
+	if err == nil && res.Value.Owner == marginfi.ProgramAddress {
+		data := res.Value.Data.GetBinary()
+		if len(data) >= marginfi.MarginfiAccountSize && bytes.Equal(data[:8], marginfi.MarginfiAccountDiscriminator[:]) {
+			return map[solana.PublicKey]*marginfi.MarginfiAccount{address: marginfi.ParseMarginfiAccount(data)}, nil
+		}
+	}
+	return marginfi.LoadAccountsByAuthority(solanaConnection, marginfi.GroupAddress, address)
+}
+
+func printReport(report *marginfi.AccountReport) {
+	fmt.Printf("account %s (authority %s)\n\n", report.AccountPK, report.Authority)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "symbol\tside\tquantity\tusd lowest\tusd none\tusd highest\tasset w init\tasset w maint\tliab w init\tliab w maint\tliq price\t")
+	for _, balance := range report.Balances {
+		liquidationPrice := "-"
+		if balance.LiquidationPrice != nil {
+			liquidationPrice = fmt.Sprintf("%.6f", *balance.LiquidationPrice)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.6f\t%.2f\t%.2f\t%.2f\t%.4f\t%.4f\t%.4f\t%.4f\t%s\t\n",
+			balance.Symbol, balance.Side, balance.Quantity,
+			balance.UsdValueLowest, balance.UsdValueNone, balance.UsdValueHighest,
+			balance.AssetWeightInit, balance.AssetWeightMaint, balance.LiabilityWeightInit, balance.LiabilityWeightMaint,
+			liquidationPrice)
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "health\tassets\tliabilities\thealth\t")
+	for _, row := range []struct {
+		name    string
+		figures marginfi.HealthFigures
+	}{
+		{"initial", report.Initial},
+		{"maintenance", report.Maintenance},
+		{"equity", report.Equity},
+	} {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t\n", row.name, row.figures.Assets, row.figures.Liabilities, row.figures.Health)
+	}
+	w.Flush()
+	fmt.Println()
+}
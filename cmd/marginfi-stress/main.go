@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"jito-bot/pkg/marginfi"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var solanaConnection *rpc.Client
+
+// defaultShocks used when no -shock flag is given
+var defaultShocks = []string{
+	"SOL=-0.1",
+	"SOL=-0.2",
+	"USDC=-0.05,USDT=-0.05",
+}
+
+// shockFlags repeated -shock SYMBOL=change[,SYMBOL=change]
+type shockFlags []string
+
+func (s *shockFlags) String() string {
+	return strings.Join(*s, " ")
+}
+
+func (s *shockFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func init() {
+	log.SetFlags(log.LUTC | log.Ldate | log.Ltime | log.Lmicroseconds)
+
+	solanaConnection = rpc.New(os.Getenv("RPC_URL"))
+}
+
+func main() {
+	var shocks shockFlags
+	flag.Var(&shocks, "shock", "price shock as SYMBOL=change[,SYMBOL=change], -0.1 is a 10% drop, repeatable")
+	jsonOutput := flag.Bool("json", false, "print report as JSON instead of tables")
+	metadataCache := flag.String("metadata-cache", "mrgn-bank-metadata-cache.json", "path of the bank metadata cache")
+	flag.Parse()
+
+	if len(shocks) == 0 {
+		shocks = defaultShocks
+	}
+
+	marginfiClient, err := marginfi.NewClient(solanaConnection)
+	if err != nil {
+		log.Fatalf("unable to load marginfi banks: %v", err)
+	}
+	// shocks are resolved by symbol so metadata is required here
+	if err := marginfiClient.LoadMetadata(*metadataCache); err != nil {
+		log.Fatalf("unable to load bank metadata: %v", err)
+	}
+	banks, oraclePrices := marginfiClient.Snapshot()
+	metadata := marginfiClient.Metadata()
+	banks = banks.WithAccruedInterest(time.Now().Unix())
+
+	priceShocks := make([]marginfi.PriceShock, 0, len(shocks))
+	for _, shock := range shocks {
+		changes, err := parseShock(shock)
+		if err != nil {
+			log.Fatalf("invalid shock %q: %v", shock, err)
+		}
+		priceShock := marginfi.NewPriceShockBySymbol(shock, changes, banks, metadata)
+		if len(priceShock.Changes) == 0 {
+			log.Fatalf("shock %q matches no bank", shock)
+		}
+		priceShocks = append(priceShocks, priceShock)
+	}
+
+	accounts, err := marginfi.LoadAccounts(solanaConnection, marginfi.GroupAddress)
+	if err != nil {
+		log.Fatalf("unable to load marginfi accounts: %v", err)
+	}
+
+	report := marginfi.AnalyzeGroupRisk(accounts, banks, oraclePrices, priceShocks, metadata)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printReport(report)
+}
+
+func parseShock(shock string) (map[string]float64, error) {
+	changes := make(map[string]float64)
+	for _, part := range strings.Split(shock, ",") {
+		symbol, change, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected SYMBOL=change, got %q", part)
+		}
+		value, err := strconv.ParseFloat(change, 64)
+		if err != nil {
+			return nil, err
+		}
+		changes[strings.TrimSpace(symbol)] = value
+	}
+	return changes, nil
+}
+
+func printReport(report *marginfi.GroupRiskReport) {
+	fmt.Printf("%d accounts, %d skipped without bank or price\n\n", report.Accounts, report.Skipped)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "symbol\taccounts\tassets usd\tliabilities usd\t")
+	for _, exposure := range report.Exposure {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t\n", exposure.Symbol, exposure.Accounts, exposure.AssetsUsd, exposure.LiabilitiesUsd)
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "health factor\taccounts\tliabilities usd\t")
+	lowerBound := 0.0
+	for _, bucket := range report.HealthDistribution {
+		label := fmt.Sprintf("%.2f - %.2f", lowerBound, bucket.UpperBound)
+		if bucket.UpperBound == 0 {
+			label = fmt.Sprintf(">= %.2f", lowerBound)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t\n", label, bucket.Accounts, bucket.LiabilitiesUsd)
+		lowerBound = bucket.UpperBound
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "shock\tliquidatable\tliabilities usd\tseized collateral usd\tprofit usd\t")
+	for _, shock := range report.Shocks {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%.2f\t\n",
+			shock.Name, shock.LiquidatableAccounts, shock.LiabilitiesUsd, shock.LiquidatableAssetUsd, shock.ProfitUsd)
+	}
+	w.Flush()
+}
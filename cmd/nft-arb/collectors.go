@@ -38,16 +38,17 @@ func PreInit() {
 
 func CollectMEM2Listings() {
 	slog.Info("collecting me v2 listings")
-	meListings, err := me.FindAllM2SellterTradeStates(solanaConnection)
+	meListings, stats, err := me.FindAllM2SellterTradeStates(solanaConnection)
 	if err != nil {
 		log.Fatalf("unable to get ME listings: %v", err)
 	}
 
-	slog.Info("found listings", "count", len(meListings))
+	slog.Info("found listings", "count", len(meListings), "malformed", stats.Malformed)
 
+	now := time.Now().Unix()
 	listingsToCollectPrice := make([]*me.M2SellerTradeState, 0, len(meListings))
 	for _, listing := range meListings {
-		if !listing.PaymentMint.IsZero() {
+		if !listing.PaymentMint.IsZero() || listing.IsExpired(now) {
 			continue
 		}
 		listingsToCollectPrice = append(listingsToCollectPrice, listing)
@@ -87,12 +88,12 @@ func CollectMEM2Listings() {
 
 func CollectMEM3Listings() {
 	slog.Info("collecting me v3 listings")
-	meListings, err := me.FindAllM3SellterTradeStates(solanaConnection)
+	meListings, stats, err := me.FindAllM3SellterTradeStates(solanaConnection)
 	if err != nil {
 		log.Fatalf("unable to get ME listings: %v", err)
 	}
 
-	slog.Info("found listings", "count", len(meListings))
+	slog.Info("found listings", "count", len(meListings), "malformed", stats.Malformed)
 
 	listingsToCollectPrice := make([]*me.M3SellerTradeState, 0, len(meListings))
 	for _, listing := range meListings {
@@ -135,6 +136,50 @@ func CollectMEM3Listings() {
 	}
 }
 
+func CollectMEMMMPools() {
+	slog.Info("collecting me mmm pools")
+	pools, stats, err := me.FindAllMMMPools(solanaConnection)
+	if err != nil {
+		log.Fatalf("unable to get ME pools: %v", err)
+	}
+
+	slog.Info("found mmm pools", "count", len(pools), "malformed", stats.Malformed)
+
+	now := time.Now().Unix()
+	for _, pool := range pools {
+		if !pool.PaymentMint.IsZero() || pool.IsExpired(now) {
+			continue
+		}
+		if !pool.Cosigner.IsZero() && pool.Cosigner != pool.Owner {
+			continue
+		}
+		collection := pool.Collection()
+		if collection == nil {
+			continue
+		}
+		askPrice, err := pool.SellPrice(1)
+		if err != nil {
+			continue
+		}
+
+		collectionId := collection.String()
+		royalty, err := rdb.HGet(ctx, "collection:"+collectionId, "royalty").Float64()
+		if err != nil {
+			// slog.Error("unable to get royalty", "collection", collectionId, "err", err)
+			continue
+		}
+		floatPrice := float64(askPrice)
+		buyPrice := uint64(math.Round(floatPrice+floatPrice*royalty+floatPrice*me.TakerFee)) + pool.LpFee(askPrice)
+		if collectionPrice, ok := magicEdenCollectionToMinPrice[collectionId]; ok {
+			if buyPrice < collectionPrice {
+				magicEdenCollectionToMinPrice[collectionId] = buyPrice
+			}
+		} else {
+			magicEdenCollectionToMinPrice[collectionId] = buyPrice
+		}
+	}
+}
+
 func CollectAssetMetadata(assets []string) {
 	isKnownSliceCmd := rdb.SMIsMember(ctx, "known_assets", assets)
 	if isKnownSliceCmd.Err() != nil {
@@ -99,6 +99,7 @@ func main() {
 	PreInit()
 	CollectMEM2Listings()
 	CollectMEM3Listings()
+	CollectMEMMMPools()
 	CollectTensorCnftBids()
 	CollectTensorNftBids()
 
@@ -0,0 +1,245 @@
+package fixed
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/Pilatuz/bigz"
+	"github.com/Pilatuz/bigz/uint256"
+)
+
+var (
+	ErrInvalidDecimal = errors.New("fixed: invalid decimal")
+	ErrInvalidFloat   = errors.New("fixed: float is not finite")
+	ErrOverflow       = errors.New("fixed: value out of range")
+)
+
+var (
+	// i80f48Min and i80f48Max raw bounds, -2^127 and 2^127 - 1
+	i80f48Min = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 127))
+	i80f48Max = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+
+	bigMultiplier2Pow48 = new(big.Int).Lsh(big.NewInt(1), i80f48FractionalBits)
+	// bigPow5Of48 turns fractional bits into 48 decimal digits, x / 2^48 == x * 5^48 / 10^48
+	bigPow5Of48 = new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(i80f48FractionalBits)), nil)
+)
+
+func I80F48FromInt64(n int64) I80F48 {
+	if n < 0 {
+		return I80F48{signExtend(negate(uint256.From64(uint64(-n)).Lsh(i80f48FractionalBits)))}
+	}
+	return I80F48FromUint64(uint64(n))
+}
+
+// I80F48FromUint64 integer amount, e.g. lamports
+func I80F48FromUint64(n uint64) I80F48 {
+	return I80F48{uint256.From64(n).Lsh(i80f48FractionalBits)}
+}
+
+// I80F48FromUint64WithDecimals is amount / 10^decimals rounded to nearest
+func I80F48FromUint64WithDecimals(amount uint64, decimals uint8) (I80F48, error) {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return I80F48FromBigRat(new(big.Rat).SetFrac(new(big.Int).SetUint64(amount), scale))
+}
+
+// I80F48FromFloat64 converts exactly, bits below 2^-48 are rounded to nearest with ties to even like rust from_num
+func I80F48FromFloat64(f float64) (I80F48, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return I80F48{}, ErrInvalidFloat
+	}
+	if f == 0 {
+		return I80F48{}, nil
+	}
+	// f = mantissa * 2^exp with 53 bit integer mantissa
+	frac, exp := math.Frexp(math.Abs(f))
+	mantissa := uint64(math.Ldexp(frac, 53))
+	shift := exp - 53 + int(i80f48FractionalBits)
+
+	var magnitude bigz.Uint256
+	switch {
+	case shift >= 0:
+		if shift+53 > 127 {
+			if f < 0 && shift+53 == 128 && mantissa == 1<<52 {
+				// exactly -2^79
+				return I80F48{signExtend(uint256.One().Lsh(127))}, nil
+			}
+			return I80F48{}, ErrOverflow
+		}
+		magnitude = uint256.From64(mantissa).Lsh(uint(shift))
+	case shift > -64:
+		magnitude = uint256.From64(roundShiftRight(mantissa, uint(-shift)))
+	default:
+		// below half of the smallest step
+		return I80F48{}, nil
+	}
+
+	if f < 0 {
+		magnitude = negate(magnitude)
+	}
+	return I80F48{signExtend(magnitude)}, nil
+}
+
+// roundShiftRight is x / 2^n rounded to nearest with ties to even, n < 64
+func roundShiftRight(x uint64, n uint) uint64 {
+	quo := x >> n
+	rem := x & (1<<n - 1)
+	half := uint64(1) << (n - 1)
+	if rem > half || (rem == half && quo&1 == 1) {
+		quo++
+	}
+	return quo
+}
+
+// I80F48FromBigInt converts an integer, ErrOverflow when it doesn't fit 80 integer bits
+func I80F48FromBigInt(n *big.Int) (I80F48, error) {
+	return fromRawBigInt(new(big.Int).Lsh(n, i80f48FractionalBits))
+}
+
+// I80F48FromBigRat rounds to nearest with ties to even
+func I80F48FromBigRat(r *big.Rat) (I80F48, error) {
+	num := new(big.Int).Lsh(r.Num(), i80f48FractionalBits)
+	quo, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	// compare 2*|rem| with denominator, quo is truncated toward zero
+	twiceRem := rem.Abs(rem).Lsh(rem, 1)
+	if cmp := twiceRem.Cmp(r.Denom()); cmp > 0 || (cmp == 0 && quo.Bit(0) == 1) {
+		if r.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return fromRawBigInt(quo)
+}
+
+// I80F48FromString parses decimal like "-12.345", digits beyond 2^-48 are rounded to nearest
+func I80F48FromString(s string) (I80F48, error) {
+	digits := s
+	negative := false
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		negative = digits[0] == '-'
+		digits = digits[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" {
+		return I80F48{}, ErrInvalidDecimal
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return I80F48{}, ErrInvalidDecimal
+			}
+		}
+	}
+
+	num, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return I80F48{}, ErrInvalidDecimal
+	}
+	if negative {
+		num.Neg(num)
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(fracPart))), nil)
+	return I80F48FromBigRat(new(big.Rat).SetFrac(num, denom))
+}
+
+func MustI80F48FromString(s string) I80F48 {
+	val, err := I80F48FromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+func fromRawBigInt(raw *big.Int) (I80F48, error) {
+	if raw.Cmp(i80f48Min) < 0 || raw.Cmp(i80f48Max) > 0 {
+		return I80F48{}, ErrOverflow
+	}
+	magnitude := uint256.FromBig(new(big.Int).Abs(raw))
+	if raw.Sign() < 0 {
+		magnitude = negate(magnitude)
+	}
+	return I80F48{signExtend(magnitude)}, nil
+}
+
+// rawBigInt is the value * 2^48
+func (u I80F48) rawBigInt() *big.Int {
+	raw := u.Abs().bits.Lo.Big()
+	if u.IsNegative() {
+		raw.Neg(raw)
+	}
+	return raw
+}
+
+// BigRat exact value
+func (u I80F48) BigRat() *big.Rat {
+	return new(big.Rat).SetFrac(u.rawBigInt(), bigMultiplier2Pow48)
+}
+
+// BigInt integer part rounded toward negative infinity like rust to_num
+func (u I80F48) BigInt() *big.Int {
+	raw := u.rawBigInt()
+	return raw.Rsh(raw, i80f48FractionalBits)
+}
+
+// Int64 integer part rounded toward negative infinity, ErrOverflow when it doesn't fit
+func (u I80F48) Int64() (int64, error) {
+	n := u.BigInt()
+	if !n.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return n.Int64(), nil
+}
+
+// Uint64 integer part rounded toward negative infinity, ErrOverflow for negative values
+func (u I80F48) Uint64() (uint64, error) {
+	n := u.BigInt()
+	if !n.IsUint64() {
+		return 0, ErrOverflow
+	}
+	return n.Uint64(), nil
+}
+
+// Uint64WithDecimals is value * 10^decimals rounded down, e.g. UI amount to native token amount
+func (u I80F48) Uint64WithDecimals(decimals uint8) (uint64, error) {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	n := new(big.Int).Mul(u.rawBigInt(), scale)
+	n.Rsh(n, i80f48FractionalBits)
+	if !n.IsUint64() {
+		return 0, ErrOverflow
+	}
+	return n.Uint64(), nil
+}
+
+// Text formats the value with precision decimal digits, last digit is rounded to nearest with halves away from zero.
+// Negative precision gives the exact value with trailing zeros removed
+func (u I80F48) Text(precision int) string {
+	if precision >= 0 {
+		return u.BigRat().FloatString(precision)
+	}
+
+	// every binary fraction has a finite decimal expansion of at most 48 digits
+	scaled := new(big.Int).Mul(u.Abs().bits.Lo.Big(), bigPow5Of48).String()
+	if len(scaled) <= int(i80f48FractionalBits) {
+		scaled = strings.Repeat("0", int(i80f48FractionalBits)-len(scaled)+1) + scaled
+	}
+	intPart, fracPart := scaled[:len(scaled)-int(i80f48FractionalBits)], strings.TrimRight(scaled[len(scaled)-int(i80f48FractionalBits):], "0")
+	text := intPart
+	if fracPart != "" {
+		text += "." + fracPart
+	}
+	if u.IsNegative() {
+		text = "-" + text
+	}
+	return text
+}
+
+// asFloat64Exact rounds to nearest float64, used when the value has more than 53 significant bits
+func (u I80F48) asFloat64Exact() float64 {
+	f, _ := new(big.Float).SetMantExp(new(big.Float).SetInt(u.Abs().bits.Lo.Big()), -int(i80f48FractionalBits)).Float64()
+	if u.IsNegative() {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,159 @@
+package fixed
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestI80F48FromString(t *testing.T) {
+	for _, test := range []struct {
+		input, output string
+	}{
+		{"0", "0"},
+		{"1.5", "1.5"},
+		{"-1.5", "-1.5"},
+		{"+0.25", "0.25"},
+		{".125", "0.125"},
+		{"100.", "100"},
+		{"604462909807314587353087", "604462909807314587353087"},
+		{"-604462909807314587353088", "-604462909807314587353088"},
+		// 2^-48 is the smallest step
+		{"0.000000000000003552713678800500929355621337890625", "0.000000000000003552713678800500929355621337890625"},
+		// below half a step rounds to zero, above half rounds up
+		{"0.0000000000000017", "0"},
+		{"0.0000000000000018", "0.000000000000003552713678800500929355621337890625"},
+	} {
+		val, err := I80F48FromString(test.input)
+		if err != nil {
+			t.Fatalf("%s: %v", test.input, err)
+		}
+		if val.String() != test.output {
+			t.Fatalf("%s: expected %s, got %s", test.input, test.output, val.String())
+		}
+	}
+
+	for _, input := range []string{"", "-", ".", "1.2.3", "1e5", "abc", "1_000", " 1"} {
+		if _, err := I80F48FromString(input); err != ErrInvalidDecimal {
+			t.Fatalf("%q: expected ErrInvalidDecimal, got %v", input, err)
+		}
+	}
+	for _, input := range []string{"604462909807314587353088", "-604462909807314587353089"} {
+		if _, err := I80F48FromString(input); err != ErrOverflow {
+			t.Fatalf("%q: expected ErrOverflow, got %v", input, err)
+		}
+	}
+}
+
+func TestI80F48FromFloat64(t *testing.T) {
+	for _, f := range []float64{0, 1, -1, 0.5, -0.75, 1e20, -1e20, 123456.789, math.Ldexp(1, -48)} {
+		val, err := I80F48FromFloat64(f)
+		if err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+		expected, err := I80F48FromBigRat(new(big.Rat).SetFloat64(f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val != expected {
+			t.Fatalf("%v: expected %s, got %s", f, expected, val)
+		}
+	}
+	// ties round to even
+	if val := MustI80F48FromFloat64(math.Ldexp(1, -49)); !val.IsZero() {
+		t.Fatalf("expected half step to round to zero, got %s", val)
+	}
+	if val := MustI80F48FromFloat64(math.Ldexp(3, -49)); val != I80F48FromUint64(1).Div64(1<<47) {
+		t.Fatalf("expected 1.5 steps to round to 2 steps, got %s", val)
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := I80F48FromFloat64(f); err != ErrInvalidFloat {
+			t.Fatalf("%v: expected ErrInvalidFloat, got %v", f, err)
+		}
+	}
+	if _, err := I80F48FromFloat64(math.Ldexp(1, 79)); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if val, err := I80F48FromFloat64(-math.Ldexp(1, 79)); err != nil || val.String() != "-604462909807314587353088" {
+		t.Fatalf("expected min value, got %s %v", val, err)
+	}
+}
+
+func TestI80F48Integers(t *testing.T) {
+	if val := I80F48FromInt64(-42); val.String() != "-42" {
+		t.Fatalf("expected -42, got %s", val)
+	}
+	if val := I80F48FromUint64(math.MaxUint64); val.String() != "18446744073709551615" {
+		t.Fatalf("expected max uint64, got %s", val)
+	}
+	for _, test := range []struct {
+		input    string
+		integer  int64
+		bigInput string
+	}{
+		{"1.75", 1, "1"},
+		{"-1.75", -2, "-2"},
+		{"-0.5", -1, "-1"},
+	} {
+		val := MustI80F48FromString(test.input)
+		if n, err := val.Int64(); err != nil || n != test.integer {
+			t.Fatalf("%s: expected %d, got %d %v", test.input, test.integer, n, err)
+		}
+		if val.BigInt().String() != test.bigInput {
+			t.Fatalf("%s: expected %s, got %s", test.input, test.bigInput, val.BigInt())
+		}
+	}
+	if _, err := MustI80F48FromString("-1").Uint64(); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow for negative, got %v", err)
+	}
+	if _, err := MustI80F48FromString("100000000000000000000").Int64(); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	minValue, _ := new(big.Int).SetString("-604462909807314587353088", 10)
+	if val, err := I80F48FromBigInt(minValue); err != nil || val.BigInt().Cmp(minValue) != 0 {
+		t.Fatalf("expected min value, got %s %v", val, err)
+	}
+}
+
+func TestI80F48Decimals(t *testing.T) {
+	val, err := I80F48FromUint64WithDecimals(1_500_000_001, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Text(9) != "1.500000001" {
+		t.Fatalf("expected 1.500000001, got %s", val.Text(9))
+	}
+	if amount, err := val.Uint64WithDecimals(9); err != nil || amount != 1_500_000_001 {
+		t.Fatalf("expected 1500000001, got %d %v", amount, err)
+	}
+	if amount, err := MustI80F48FromString("2.999").Uint64WithDecimals(2); err != nil || amount != 299 {
+		t.Fatalf("expected amount rounded down to 299, got %d %v", amount, err)
+	}
+	if _, err := MustI80F48FromString("-1").Uint64WithDecimals(6); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow for negative, got %v", err)
+	}
+}
+
+func TestI80F48Text(t *testing.T) {
+	val := MustI80F48FromString("-2.125")
+	for precision, expected := range map[int]string{0: "-2", 1: "-2.1", 2: "-2.13", 4: "-2.1250"} {
+		if text := val.Text(precision); text != expected {
+			t.Fatalf("precision %d: expected %s, got %s", precision, expected, text)
+		}
+	}
+	if ratio := val.BigRat(); ratio.Cmp(big.NewRat(-17, 8)) != 0 {
+		t.Fatalf("expected -17/8, got %s", ratio)
+	}
+}
+
+func TestAsFloat64LargeValues(t *testing.T) {
+	val := MustI80F48FromString("123456789012345678901.5")
+	ratio, _ := new(big.Rat).SetString("123456789012345678901.5")
+	expected, _ := ratio.Float64()
+	if val.AsFloat64() != expected {
+		t.Fatalf("expected %v, got %v", expected, val.AsFloat64())
+	}
+	if val.Neg().AsFloat64() != -expected {
+		t.Fatalf("expected %v, got %v", -expected, val.Neg().AsFloat64())
+	}
+}
@@ -0,0 +1,83 @@
+package fixed
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Pilatuz/bigz/uint128"
+	bin "github.com/gagliardetto/binary"
+)
+
+// I80F48Size on-chain size, 16 bytes little endian two's complement
+const I80F48Size = 16
+
+var ErrInvalidLength = errors.New("fixed: invalid I80F48 length")
+
+var (
+	_ json.Marshaler        = I80F48{}
+	_ json.Unmarshaler      = (*I80F48)(nil)
+	_ bin.BinaryMarshaler   = I80F48{}
+	_ bin.BinaryUnmarshaler = (*I80F48)(nil)
+)
+
+// I80F48FromLittleEndian is MustI80F48FromLittleEndian with length check, extra bytes are ignored
+func I80F48FromLittleEndian(data []byte) (I80F48, error) {
+	if len(data) < I80F48Size {
+		return I80F48{}, ErrInvalidLength
+	}
+	return MustI80F48FromLittleEndian(data), nil
+}
+
+// PutLittleEndian writes 16 bytes, panics when b is shorter
+func (u I80F48) PutLittleEndian(b []byte) {
+	uint128.StoreLittleEndian(b[:I80F48Size], u.bits.Lo)
+}
+
+func (u I80F48) LittleEndian() []byte {
+	b := make([]byte, I80F48Size)
+	u.PutLittleEndian(b)
+	return b
+}
+
+// MarshalJSON encodes exact decimal string, numbers are avoided as JSON parsers usually read them into float64
+func (u I80F48) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+// UnmarshalJSON accepts decimal string or plain number, null is a no-op like for other json types
+func (u *I80F48) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var text string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	} else {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return err
+		}
+		text = number.String()
+	}
+	val, err := I80F48FromString(text)
+	if err != nil {
+		return err
+	}
+	*u = val
+	return nil
+}
+
+func (u I80F48) MarshalWithEncoder(encoder *bin.Encoder) error {
+	return encoder.WriteBytes(u.LittleEndian(), false)
+}
+
+func (u *I80F48) UnmarshalWithDecoder(decoder *bin.Decoder) error {
+	data, err := decoder.ReadNBytes(I80F48Size)
+	if err != nil {
+		return err
+	}
+	*u = MustI80F48FromLittleEndian(data)
+	return nil
+}
@@ -0,0 +1,99 @@
+package fixed
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+)
+
+func TestLittleEndianRoundTrip(t *testing.T) {
+	for _, val := range []I80F48{I80F48{}, I80f48One, MustI80F48FromString("-1.5"), I80f48Min, I80f48Max, I80f48Delta.Neg()} {
+		data := val.LittleEndian()
+		if len(data) != I80F48Size {
+			t.Fatalf("expected %d bytes, got %d", I80F48Size, len(data))
+		}
+		decoded, err := I80F48FromLittleEndian(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != val {
+			t.Fatalf("expected %s, got %s", val, decoded)
+		}
+	}
+	// -1.5 is -(3 << 47)
+	expected := []byte{0, 0, 0, 0, 0, 0x80, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if data := MustI80F48FromString("-1.5").LittleEndian(); !bytes.Equal(data, expected) {
+		t.Fatalf("unexpected bytes %x", data)
+	}
+	if _, err := I80F48FromLittleEndian(make([]byte, I80F48Size-1)); err != ErrInvalidLength {
+		t.Fatalf("expected ErrInvalidLength, got %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type snapshot struct {
+		Price I80F48  `json:"price"`
+		Share *I80F48 `json:"share"`
+	}
+	share := MustI80F48FromString("1.000123")
+	data, err := json.Marshal(snapshot{Price: MustI80F48FromString("-123.25"), Share: &share})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":"-123.25","share":"`+share.String()+`"}` {
+		t.Fatalf("unexpected json %s", data)
+	}
+
+	var decoded snapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Price != MustI80F48FromString("-123.25") || *decoded.Share != share {
+		t.Fatalf("unexpected decoded %+v", decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"price":42.5}`), &decoded); err != nil || decoded.Price != MustI80F48FromString("42.5") {
+		t.Fatalf("expected number to decode, got %s %v", decoded.Price, err)
+	}
+	if err := json.Unmarshal([]byte(`{"price":"abc"}`), &decoded); err == nil {
+		t.Fatal("expected invalid decimal error")
+	}
+
+	// null keeps the value, pointer is reset by encoding/json itself
+	if err := json.Unmarshal([]byte(`{"price":null,"share":null}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Price != MustI80F48FromString("42.5") || decoded.Share != nil {
+		t.Fatalf("unexpected decoded after null %+v", decoded)
+	}
+}
+
+func TestBorsh(t *testing.T) {
+	type account struct {
+		Flag  uint8
+		Value I80F48
+		Tail  uint64
+	}
+	expected := account{Flag: 1, Value: MustI80F48FromString("-0.75"), Tail: 7}
+
+	buf := new(bytes.Buffer)
+	if err := bin.NewBorshEncoder(buf).Encode(expected); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 1+I80F48Size+8 {
+		t.Fatalf("unexpected size %d", buf.Len())
+	}
+	if !bytes.Equal(buf.Bytes()[1:1+I80F48Size], expected.Value.LittleEndian()) {
+		t.Fatalf("unexpected value bytes %x", buf.Bytes())
+	}
+
+	var decoded account
+	if err := bin.NewBorshDecoder(buf.Bytes()).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != expected {
+		t.Fatalf("expected %+v, got %+v", expected, decoded)
+	}
+}
@@ -1,96 +1,171 @@
 package fixed
 
 import (
-	"fmt"
 	"github.com/Pilatuz/bigz"
 	"github.com/Pilatuz/bigz/uint128"
 	"github.com/Pilatuz/bigz/uint256"
-	"math"
 )
 
 const i80f48FractionalBits uint = 48
 
 var (
-	multiplier2Pow48              = uint256.From64(281474976710656)
 	multiplier2Pow48Float float64 = 281474976710656
 
+	I80f48One = I80F48{uint256.One().Lsh(i80f48FractionalBits)}
+
 	I80f48Pow10 = [...]I80F48{
-		MustI80F48FromFloat64(math.Pow10(0)),
-		MustI80F48FromFloat64(math.Pow10(1)),
-		MustI80F48FromFloat64(math.Pow10(2)),
-		MustI80F48FromFloat64(math.Pow10(3)),
-		MustI80F48FromFloat64(math.Pow10(4)),
-		MustI80F48FromFloat64(math.Pow10(5)),
-		MustI80F48FromFloat64(math.Pow10(6)),
-		MustI80F48FromFloat64(math.Pow10(7)),
-		MustI80F48FromFloat64(math.Pow10(8)),
-		MustI80F48FromFloat64(math.Pow10(9)),
-		MustI80F48FromFloat64(math.Pow10(10)),
-		MustI80F48FromFloat64(math.Pow10(11)),
-		MustI80F48FromFloat64(math.Pow10(12)),
-		MustI80F48FromFloat64(math.Pow10(13)),
-		MustI80F48FromFloat64(math.Pow10(14)),
-		MustI80F48FromFloat64(math.Pow10(15)),
-		MustI80F48FromFloat64(math.Pow10(16)),
+		I80F48FromUint64(1),
+		I80F48FromUint64(10),
+		I80F48FromUint64(100),
+		I80F48FromUint64(1_000),
+		I80F48FromUint64(10_000),
+		I80F48FromUint64(100_000),
+		I80F48FromUint64(1_000_000),
+		I80F48FromUint64(10_000_000),
+		I80F48FromUint64(100_000_000),
+		I80F48FromUint64(1_000_000_000),
+		I80F48FromUint64(10_000_000_000),
+		I80F48FromUint64(100_000_000_000),
+		I80F48FromUint64(1_000_000_000_000),
+		I80F48FromUint64(10_000_000_000_000),
+		I80F48FromUint64(100_000_000_000_000),
+		I80F48FromUint64(1_000_000_000_000_000),
+		I80F48FromUint64(10_000_000_000_000_000),
 	}
 )
 
+// I80F48 is a signed fixed point number with 48 fractional bits, same as rust fixed::types::I80F48.
+// The 128-bit two's complement value is kept sign extended to 256 bits so intermediate results don't overflow,
+// results wrap around to 128 bits like the rust type does in release builds
 type I80F48 struct {
-	bigz.Uint256
+	bits bigz.Uint256
 }
 
+// MustI80F48FromLittleEndian loads 16 bytes of two's complement value
 func MustI80F48FromLittleEndian(data []byte) I80F48 {
-	return I80F48{uint256.From128(uint128.LoadLittleEndian(data))}
+	return I80F48{signExtend(uint256.From128(uint128.LoadLittleEndian(data)))}
 }
 
+// MustI80F48FromFloat64 panics when the float is not finite or out of range, see I80F48FromFloat64
 func MustI80F48FromFloat64(data float64) I80F48 {
-	intPart := math.Trunc(data)
-	val := uint256.From64(uint64(intPart)).Lsh(i80f48FractionalBits)
-	val = val.Add(uint256.From64(uint64((data - intPart) * multiplier2Pow48Float)))
-	return I80F48{val}
+	val, err := I80F48FromFloat64(data)
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
 func (u I80F48) Add(n I80F48) I80F48 {
-	return I80F48{u.Uint256.Add(n.Uint256)}
+	return I80F48{signExtend(u.bits.Add(n.bits))}
 }
 
 func (u I80F48) Sub(n I80F48) I80F48 {
-	return I80F48{u.Uint256.Sub(n.Uint256)}
+	return I80F48{signExtend(u.bits.Sub(n.bits))}
 }
 
+// Mul rounds toward negative infinity like the rust type, product of two sign extended values fits 256 bits
 func (u I80F48) Mul(n I80F48) I80F48 {
-	return I80F48{u.Uint256.Mul(n.Uint256).Rsh(i80f48FractionalBits)}
+	return I80F48{signExtend(arithmeticRsh(u.bits.Mul(n.bits), i80f48FractionalBits))}
 }
 
 func (u I80F48) Mul64(n uint64) I80F48 {
-	return I80F48{u.Uint256.Mul128(uint128.From64(n)).Rsh(i80f48FractionalBits)}
+	return I80F48{signExtend(u.bits.Mul128(uint128.From64(n)))}
 }
 
+// Div truncates toward zero like the rust type, panics when n is zero
 func (u I80F48) Div(n I80F48) I80F48 {
-	return I80F48{u.Uint256.Lsh(i80f48FractionalBits).Div(n.Uint256)}
+	return I80F48{signExtend(u.signedQuo(n))}
 }
 
 func (u I80F48) Div64(n uint64) I80F48 {
-	return I80F48{u.Uint256.Lsh(i80f48FractionalBits).Div64(n)}
+	quo := bigz.Uint256{Lo: u.Abs().bits.Lo}.Div64(n)
+	if u.IsNegative() {
+		quo = negate(quo)
+	}
+	return I80F48{signExtend(quo)}
+}
+
+func (u I80F48) Neg() I80F48 {
+	return I80F48{signExtend(negate(u.bits))}
+}
+
+// Abs of the minimum value wraps to itself like the rust type
+func (u I80F48) Abs() I80F48 {
+	if u.IsNegative() {
+		return u.Neg()
+	}
+	return u
+}
+
+func (u I80F48) IsNegative() bool {
+	return isNegative(u.bits)
+}
+
+func (u I80F48) IsZero() bool {
+	return u.bits.IsZero()
 }
 
 func (u I80F48) LessThan(n I80F48) bool {
-	return u.Uint256.Cmp(n.Uint256) < 0
+	return compare(u.bits, n.bits) < 0
+}
+
+func (u I80F48) LessThanOrEqual(n I80F48) bool {
+	return compare(u.bits, n.bits) <= 0
 }
 
 func (u I80F48) BiggerThanOrEqual(n I80F48) bool {
-	return u.Uint256.Cmp(n.Uint256) >= 0
+	return compare(u.bits, n.bits) >= 0
 }
 
+// AsFloat64 rounds to nearest float64
 func (u I80F48) AsFloat64() float64 {
-	div, mod := u.QuoRem(multiplier2Pow48)
-
-	res := float64(div.Lo.Lo)
-	res += float64(mod.Lo.Lo) / multiplier2Pow48Float
-
+	abs := u.Abs().bits.Lo
+	// values with up to 53 significant bits convert exactly
+	if abs.Hi != 0 || abs.Lo >= 1<<53 {
+		return u.asFloat64Exact()
+	}
+	res := float64(abs.Lo) / multiplier2Pow48Float
+	if u.IsNegative() {
+		return -res
+	}
 	return res
 }
 
+// String exact decimal value
 func (u I80F48) String() string {
-	return fmt.Sprintf("%v", u.AsFloat64())
+	return u.Text(-1)
+}
+
+func isNegative(x bigz.Uint256) bool {
+	return x.Hi.Hi>>63 == 1
+}
+
+func negate(x bigz.Uint256) bigz.Uint256 {
+	return x.Not().Add(uint256.One())
+}
+
+// signExtend keeps the low 128 bits and copies bit 127 to the upper half
+func signExtend(x bigz.Uint256) bigz.Uint256 {
+	if x.Lo.Hi>>63 == 1 {
+		return bigz.Uint256{Lo: x.Lo, Hi: uint128.Max()}
+	}
+	return bigz.Uint256{Lo: x.Lo}
+}
+
+func arithmeticRsh(x bigz.Uint256, n uint) bigz.Uint256 {
+	if !isNegative(x) {
+		return x.Rsh(n)
+	}
+	return x.Rsh(n).Or(uint256.Max().Lsh(256 - n))
+}
+
+// compare is signed comparison of two's complement values
+func compare(x, y bigz.Uint256) int {
+	if xNeg, yNeg := isNegative(x), isNegative(y); xNeg != yNeg {
+		if xNeg {
+			return -1
+		}
+		return 1
+	}
+	return x.Cmp(y)
 }
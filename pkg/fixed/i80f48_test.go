@@ -1,7 +1,12 @@
 package fixed
 
 import (
+	"math"
 	"testing"
+
+	"github.com/Pilatuz/bigz"
+	"github.com/Pilatuz/bigz/uint128"
+	"github.com/Pilatuz/bigz/uint256"
 )
 
 func TestMustI80F48FromFloat64(t *testing.T) {
@@ -31,3 +36,75 @@ func TestMul(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSignedLittleEndian(t *testing.T) {
+	// -1.5 as I80F48 is -(3 << 47)
+	data := []byte{0, 0, 0, 0, 0, 0x80, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	val := MustI80F48FromLittleEndian(data)
+	if !val.IsNegative() || val.AsFloat64() != -1.5 {
+		t.Fatalf("expected -1.5, got %v", val.AsFloat64())
+	}
+	if val != MustI80F48FromFloat64(-1.5) {
+		t.Fatal("float and little endian loading differ")
+	}
+}
+
+func TestSignedArithmetic(t *testing.T) {
+	a, b := MustI80F48FromFloat64(1.25), MustI80F48FromFloat64(3.5)
+	if diff := a.Sub(b); diff.AsFloat64() != -2.25 {
+		t.Fatalf("1.25 - 3.5 = %v", diff.AsFloat64())
+	}
+	if product := a.Neg().Mul(b); product.AsFloat64() != -4.375 {
+		t.Fatalf("-1.25 * 3.5 = %v", product.AsFloat64())
+	}
+	if product := a.Neg().Mul(b.Neg()); product.AsFloat64() != 4.375 {
+		t.Fatalf("-1.25 * -3.5 = %v", product.AsFloat64())
+	}
+	if quotient := b.Neg().Div(a); math.Abs(quotient.AsFloat64()+2.8) > 1e-12 {
+		t.Fatalf("-3.5 / 1.25 = %v", quotient.AsFloat64())
+	}
+	if quotient := b.Neg().Div64(2); quotient.AsFloat64() != -1.75 {
+		t.Fatalf("-3.5 / 2 = %v", quotient.AsFloat64())
+	}
+	if product := a.Neg().Mul64(4); product.AsFloat64() != -5 {
+		t.Fatalf("-1.25 * 4 = %v", product.AsFloat64())
+	}
+	if abs := a.Neg().Abs(); abs != a {
+		t.Fatalf("|-1.25| = %v", abs.AsFloat64())
+	}
+}
+
+func TestSignedRounding(t *testing.T) {
+	// smallest positive value
+	delta := I80F48{uint256.One()}
+	half := MustI80F48FromFloat64(0.5)
+	// mul rounds toward negative infinity, div toward zero
+	if product := delta.Neg().Mul(half); product != delta.Neg() {
+		t.Fatalf("-delta * 0.5 should round down to -delta, got %v", product.bits)
+	}
+	if quotient := delta.Neg().Div(MustI80F48FromFloat64(2)); !quotient.IsZero() {
+		t.Fatalf("-delta / 2 should truncate to zero, got %v", quotient.bits)
+	}
+}
+
+func TestSignedComparison(t *testing.T) {
+	negative, zero, positive := MustI80F48FromFloat64(-2), I80F48{}, MustI80F48FromFloat64(1)
+	if !negative.LessThan(zero) || !negative.LessThan(positive) || !zero.LessThan(positive) {
+		t.Fatal("expected -2 < 0 < 1")
+	}
+	if positive.LessThanOrEqual(negative) || !positive.BiggerThanOrEqual(negative) {
+		t.Fatal("expected 1 > -2")
+	}
+	if !MustI80F48FromFloat64(-3).LessThan(negative) {
+		t.Fatal("expected -3 < -2")
+	}
+}
+
+func TestWrapping(t *testing.T) {
+	// max value is 2^79 - 2^-48, adding delta wraps to the min value like rust in release builds
+	max := I80F48{bigz.Uint256{Lo: uint128.Max().Rsh(1)}}
+	wrapped := max.Add(I80F48{uint256.One()})
+	if !wrapped.IsNegative() || wrapped != wrapped.Abs() {
+		t.Fatalf("expected wrap to min value, got %v", wrapped.bits)
+	}
+}
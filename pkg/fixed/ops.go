@@ -0,0 +1,140 @@
+package fixed
+
+import (
+	"github.com/Pilatuz/bigz"
+	"github.com/Pilatuz/bigz/uint128"
+	"github.com/Pilatuz/bigz/uint256"
+)
+
+var (
+	// I80f48Min is -2^79, I80f48Max is 2^79 - 2^-48
+	I80f48Min = I80F48{signExtend(uint256.One().Lsh(127))}
+	I80f48Max = I80F48{bigz.Uint256{Lo: uint128.Max().Rsh(1)}}
+	// I80f48Delta is the smallest positive value, 2^-48
+	I80f48Delta = I80F48{uint256.One()}
+
+	fractionalMask = uint256.One().Lsh(i80f48FractionalBits).Sub(uint256.One())
+	i80f48Half     = uint256.One().Lsh(i80f48FractionalBits - 1)
+)
+
+// inRange tells if the 256-bit result fits I80F48, i.e. it's already sign extended from 128 bits
+func inRange(x bigz.Uint256) bool {
+	return x.Equals(signExtend(x))
+}
+
+// checked returns zero and false when the 256-bit result doesn't fit
+func checked(x bigz.Uint256) (I80F48, bool) {
+	if !inRange(x) {
+		return I80F48{}, false
+	}
+	return I80F48{x}, true
+}
+
+// saturate returns the bound on the side of the unbounded result
+func saturate(x bigz.Uint256) I80F48 {
+	if inRange(x) {
+		return I80F48{x}
+	}
+	if isNegative(x) {
+		return I80f48Min
+	}
+	return I80f48Max
+}
+
+func (u I80F48) CheckedAdd(n I80F48) (I80F48, bool) {
+	return checked(u.bits.Add(n.bits))
+}
+
+func (u I80F48) CheckedSub(n I80F48) (I80F48, bool) {
+	return checked(u.bits.Sub(n.bits))
+}
+
+func (u I80F48) CheckedMul(n I80F48) (I80F48, bool) {
+	return checked(arithmeticRsh(u.bits.Mul(n.bits), i80f48FractionalBits))
+}
+
+// CheckedDiv is false for zero divisor and overflow, quotient truncates toward zero
+func (u I80F48) CheckedDiv(n I80F48) (I80F48, bool) {
+	if n.IsZero() {
+		return I80F48{}, false
+	}
+	return checked(u.signedQuo(n))
+}
+
+func (u I80F48) SaturatingAdd(n I80F48) I80F48 {
+	return saturate(u.bits.Add(n.bits))
+}
+
+func (u I80F48) SaturatingSub(n I80F48) I80F48 {
+	return saturate(u.bits.Sub(n.bits))
+}
+
+func (u I80F48) SaturatingMul(n I80F48) I80F48 {
+	return saturate(arithmeticRsh(u.bits.Mul(n.bits), i80f48FractionalBits))
+}
+
+// SaturatingDiv panics when n is zero like the rust type
+func (u I80F48) SaturatingDiv(n I80F48) I80F48 {
+	return saturate(u.signedQuo(n))
+}
+
+// signedQuo is the unbounded quotient truncated toward zero
+func (u I80F48) signedQuo(n I80F48) bigz.Uint256 {
+	// magnitudes are taken from the low 128 bits so Abs of the minimum value doesn't wrap
+	quo := bigz.Uint256{Lo: u.Abs().bits.Lo}.Lsh(i80f48FractionalBits).Div(bigz.Uint256{Lo: n.Abs().bits.Lo})
+	if u.IsNegative() != n.IsNegative() {
+		quo = negate(quo)
+	}
+	return quo
+}
+
+// Floor rounds toward negative infinity
+func (u I80F48) Floor() I80F48 {
+	return I80F48{u.bits.AndNot(fractionalMask)}
+}
+
+// Ceil rounds toward positive infinity, wraps around above the maximum integer
+func (u I80F48) Ceil() I80F48 {
+	return I80F48{signExtend(u.bits.Add(fractionalMask).AndNot(fractionalMask))}
+}
+
+// Round rounds to nearest with ties away from zero like rust round, wraps around above the maximum integer
+func (u I80F48) Round() I80F48 {
+	abs := bigz.Uint256{Lo: u.Abs().bits.Lo}.Add(i80f48Half).AndNot(fractionalMask)
+	if u.IsNegative() {
+		abs = negate(abs)
+	}
+	return I80F48{signExtend(abs)}
+}
+
+// RoundTiesToEven rounds to nearest with ties to the even integer
+func (u I80F48) RoundTiesToEven() I80F48 {
+	floor := u.Floor()
+	frac := u.bits.And(fractionalMask)
+	if cmp := frac.Cmp(i80f48Half); cmp > 0 || (cmp == 0 && floor.bits.Rsh(i80f48FractionalBits).Lo.Lo&1 == 1) {
+		return floor.Add(I80f48One)
+	}
+	return floor
+}
+
+func (u I80F48) Cmp(n I80F48) int {
+	return compare(u.bits, n.bits)
+}
+
+func (u I80F48) Eq(n I80F48) bool {
+	return u.bits.Equals(n.bits)
+}
+
+func (u I80F48) Min(n I80F48) I80F48 {
+	if n.LessThan(u) {
+		return n
+	}
+	return u
+}
+
+func (u I80F48) Max(n I80F48) I80F48 {
+	if u.LessThan(n) {
+		return n
+	}
+	return u
+}
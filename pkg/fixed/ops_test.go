@@ -0,0 +1,164 @@
+package fixed
+
+import (
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/Pilatuz/bigz"
+)
+
+// Generate random values of varying magnitude so both overflowing and regular results are covered
+func (I80F48) Generate(rand *rand.Rand, size int) reflect.Value {
+	raw := new(big.Int).Rand(rand, new(big.Int).Lsh(big.NewInt(1), uint(1+rand.Intn(128))))
+	if rand.Intn(2) == 0 {
+		raw.Neg(raw)
+	}
+	if raw.Cmp(i80f48Min) < 0 {
+		raw.Set(i80f48Min)
+	}
+	if raw.Cmp(i80f48Max) > 0 {
+		raw.Set(i80f48Max)
+	}
+	val, err := fromRawBigInt(raw)
+	if err != nil {
+		panic(err)
+	}
+	return reflect.ValueOf(val)
+}
+
+// expectChecked compares the checked result with the exact raw result
+func expectChecked(val I80F48, ok bool, raw *big.Int) bool {
+	if raw.Cmp(i80f48Min) < 0 || raw.Cmp(i80f48Max) > 0 {
+		return !ok
+	}
+	return ok && val.rawBigInt().Cmp(raw) == 0
+}
+
+// expectSaturating compares the saturating result with the exact raw result clamped to the range
+func expectSaturating(val I80F48, raw *big.Int) bool {
+	switch {
+	case raw.Cmp(i80f48Min) < 0:
+		return val == I80f48Min
+	case raw.Cmp(i80f48Max) > 0:
+		return val == I80f48Max
+	}
+	return val.rawBigInt().Cmp(raw) == 0
+}
+
+func TestCheckedAndSaturatingProperties(t *testing.T) {
+	config := &quick.Config{MaxCount: 5000}
+	properties := map[string]any{
+		"add": func(a, b I80F48) bool {
+			raw := new(big.Int).Add(a.rawBigInt(), b.rawBigInt())
+			val, ok := a.CheckedAdd(b)
+			return expectChecked(val, ok, raw) && expectSaturating(a.SaturatingAdd(b), raw)
+		},
+		"sub": func(a, b I80F48) bool {
+			raw := new(big.Int).Sub(a.rawBigInt(), b.rawBigInt())
+			val, ok := a.CheckedSub(b)
+			return expectChecked(val, ok, raw) && expectSaturating(a.SaturatingSub(b), raw)
+		},
+		"mul": func(a, b I80F48) bool {
+			// rounds toward negative infinity
+			raw := new(big.Int).Mul(a.rawBigInt(), b.rawBigInt())
+			raw.Rsh(raw, i80f48FractionalBits)
+			val, ok := a.CheckedMul(b)
+			return expectChecked(val, ok, raw) && expectSaturating(a.SaturatingMul(b), raw)
+		},
+		"div": func(a, b I80F48) bool {
+			if b.IsZero() {
+				_, ok := a.CheckedDiv(b)
+				return !ok
+			}
+			// truncates toward zero
+			raw := new(big.Int).Lsh(a.rawBigInt(), i80f48FractionalBits)
+			raw.Quo(raw, b.rawBigInt())
+			val, ok := a.CheckedDiv(b)
+			return expectChecked(val, ok, raw) && expectSaturating(a.SaturatingDiv(b), raw)
+		},
+		"wrapping add matches checked in range": func(a, b I80F48) bool {
+			val, ok := a.CheckedAdd(b)
+			return !ok || a.Add(b) == val
+		},
+		"cmp": func(a, b I80F48) bool {
+			return a.Cmp(b) == a.rawBigInt().Cmp(b.rawBigInt()) &&
+				a.Eq(b) == (a.Cmp(b) == 0) &&
+				a.Min(b).LessThanOrEqual(a.Max(b))
+		},
+		"rounding": func(a I80F48) bool {
+			rat := a.BigRat()
+			floor := new(big.Int).Div(rat.Num(), rat.Denom()) // euclidean, denominator is positive
+			ceil := new(big.Int).Set(floor)
+			if !rat.IsInt() {
+				ceil.Add(ceil, big.NewInt(1))
+			}
+			if a.Floor().BigRat().Cmp(new(big.Rat).SetInt(floor)) != 0 {
+				return false
+			}
+			// ceil of values above the maximum integer wraps
+			if ceil.Cmp(new(big.Int).Rsh(i80f48Max, i80f48FractionalBits)) <= 0 && a.Ceil().BigRat().Cmp(new(big.Rat).SetInt(ceil)) != 0 {
+				return false
+			}
+			diff := new(big.Rat).Sub(a.Round().BigRat(), rat)
+			// round of values above the maximum integer wraps
+			return diff.Abs(diff).Cmp(big.NewRat(1, 2)) <= 0 || a.Round().IsNegative() != a.IsNegative()
+		},
+	}
+	for name, property := range properties {
+		if err := quick.Check(property, config); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+// TestReferenceVectors edge cases following rust fixed::types::I80F48 semantics
+func TestReferenceVectors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		got      I80F48
+		expected string
+	}{
+		{"floor(-2.5)", MustI80F48FromString("-2.5").Floor(), "-3"},
+		{"ceil(-2.5)", MustI80F48FromString("-2.5").Ceil(), "-2"},
+		{"round(-2.5)", MustI80F48FromString("-2.5").Round(), "-3"},
+		{"round(2.5)", MustI80F48FromString("2.5").Round(), "3"},
+		{"round_ties_to_even(2.5)", MustI80F48FromString("2.5").RoundTiesToEven(), "2"},
+		{"round_ties_to_even(-3.5)", MustI80F48FromString("-3.5").RoundTiesToEven(), "-4"},
+		{"round_ties_to_even(-2.25)", MustI80F48FromString("-2.25").RoundTiesToEven(), "-2"},
+		{"ceil(1 + delta)", I80f48One.Add(I80f48Delta).Ceil(), "2"},
+		{"MIN", I80f48Min, "-604462909807314587353088"},
+		{"MAX", I80f48Max, "604462909807314587353087.999999999999996447286321199499070644378662109375"},
+		{"saturating_add(MAX, delta)", I80f48Max.SaturatingAdd(I80f48Delta), I80f48Max.String()},
+		{"saturating_sub(MIN, delta)", I80f48Min.SaturatingSub(I80f48Delta), I80f48Min.String()},
+		{"saturating_mul(MIN, -1)", I80f48Min.SaturatingMul(I80f48One.Neg()), I80f48Max.String()},
+		{"saturating_div(MIN, -1)", I80f48Min.SaturatingDiv(I80f48One.Neg()), I80f48Max.String()},
+		{"wrapping_abs(MIN)", I80f48Min.Abs(), I80f48Min.String()},
+		{"-1 * delta", I80f48One.Neg().Mul(I80f48Delta), "-0.000000000000003552713678800500929355621337890625"},
+		{"delta * -0.5", I80f48Delta.Mul(MustI80F48FromString("-0.5")), "-0.000000000000003552713678800500929355621337890625"},
+		{"-delta / 2", I80f48Delta.Neg().Div(MustI80F48FromString("2")), "0"},
+		{"1 / 3", I80f48One.Div(MustI80F48FromString("3")), "0.333333333333332149095440399833023548126220703125"},
+	} {
+		if test.got.String() != test.expected && test.got.Text(48) != test.expected {
+			t.Fatalf("%s: expected %s, got %s", test.name, test.expected, test.got)
+		}
+	}
+
+	if _, ok := I80f48Max.CheckedAdd(I80f48Delta); ok {
+		t.Fatal("checked_add(MAX, delta) should overflow")
+	}
+	if _, ok := I80f48One.CheckedDiv(I80F48{}); ok {
+		t.Fatal("checked_div by zero should fail")
+	}
+	if _, ok := I80f48Min.CheckedDiv(I80f48One.Neg()); ok {
+		t.Fatal("checked_div(MIN, -1) should overflow")
+	}
+	if val, ok := I80f48Min.CheckedMul(I80f48One); !ok || val != I80f48Min {
+		t.Fatal("checked_mul(MIN, 1) should be MIN")
+	}
+	if I80f48Min.Cmp(I80f48Max) != -1 || !I80f48Max.Eq(I80F48{bigz.Uint256{Lo: I80f48Max.bits.Lo}}) {
+		t.Fatal("unexpected comparison of bounds")
+	}
+}
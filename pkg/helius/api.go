@@ -22,13 +22,31 @@ func (c *HeliusClient) GetAssetsBatch(ids []string) (*fastjson.Value, error) {
 		return nil, errors.New("batch size is too big")
 	}
 
+	return c.call("GetAssetsBatch_"+ids[0], "getAssetBatch", map[string]interface{}{
+		"ids": ids,
+	})
+}
+
+func (c *HeliusClient) GetAsset(id string) (*fastjson.Value, error) {
+	return c.call("GetAsset_"+id, "getAsset", map[string]interface{}{
+		"id": id,
+	})
+}
+
+// GetAssetProof merkle proof of compressed asset, proof goes from leaf to root
+func (c *HeliusClient) GetAssetProof(id string) (*fastjson.Value, error) {
+	return c.call("GetAssetProof_"+id, "getAssetProof", map[string]interface{}{
+		"id": id,
+	})
+}
+
+// call returned value is valid until the next call
+func (c *HeliusClient) call(id string, method string, params map[string]interface{}) (*fastjson.Value, error) {
 	body, _ := json.Marshal(map[string]interface{}{
 		"jsonrpc": "2.0",
-		"id":      "GetAssetsBatch_" + ids[0],
-		"method":  "getAssetBatch",
-		"params": map[string]interface{}{
-			"ids": ids,
-		},
+		"id":      id,
+		"method":  method,
+		"params":  params,
 	})
 
 	resp, err := http.Post(c.Url, "application/json", bytes.NewBuffer(body))
@@ -0,0 +1,38 @@
+package jito
+
+import (
+	mev "jito-bot/pkg/jito/gen"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+const PacketAddr = "0.0.0.0"
+
+// MakeBundle packs already signed transactions in order, bundle is executed atomically
+func MakeBundle(txs ...*solana.Transaction) (*mev.Bundle, error) {
+	packets := make([]*mev.Packet, 0, len(txs))
+	for _, tx := range txs {
+		txData, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, &mev.Packet{
+			Data: txData,
+			Meta: &mev.Meta{
+				Port:        0,
+				Addr:        PacketAddr,
+				SenderStake: 0,
+				Size:        uint64(len(txData)),
+			},
+		})
+	}
+
+	return &mev.Bundle{
+		Packets: packets,
+	}, nil
+}
+
+func MakeTipIx(payer solana.PublicKey) solana.Instruction {
+	return system.NewTransferInstruction(JitoTipLamports, payer, GetRandomJitoTipAccount()).Build()
+}
@@ -0,0 +1,167 @@
+package jupiter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/valyala/fastjson"
+)
+
+const DefaultUrl = "https://quote-api.jup.ag/v6"
+
+type JupiterClient struct {
+	Url    string
+	parser fastjson.Parser
+}
+
+type Quote struct {
+	InAmount  uint64
+	OutAmount uint64
+	// OtherAmountThreshold is the minimum out amount the swap guarantees for ExactIn quotes, slippage included
+	OtherAmountThreshold uint64
+	// Raw is passed back as is to swap-instructions
+	Raw []byte
+}
+
+type SwapInstructions struct {
+	SetupInstructions   []solana.Instruction
+	SwapInstruction     solana.Instruction
+	CleanupInstruction  solana.Instruction // nil if not needed
+	AddressLookupTables []solana.PublicKey
+}
+
+func (c *JupiterClient) GetQuote(inputMint solana.PublicKey, outputMint solana.PublicKey, amount uint64, slippageBps uint16, maxAccounts int) (*Quote, error) {
+	query := url.Values{}
+	query.Set("inputMint", inputMint.String())
+	query.Set("outputMint", outputMint.String())
+	query.Set("amount", strconv.FormatUint(amount, 10))
+	query.Set("slippageBps", strconv.FormatUint(uint64(slippageBps), 10))
+	if maxAccounts > 0 {
+		query.Set("maxAccounts", strconv.Itoa(maxAccounts))
+	}
+
+	resp, err := http.Get(c.Url + "/quote?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("quote failed: %s", body)
+	}
+
+	quote, err := c.parser.ParseBytes(body)
+	if err != nil {
+		return nil, err
+	}
+	inAmount, err := strconv.ParseUint(string(quote.GetStringBytes("inAmount")), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	outAmount, err := strconv.ParseUint(string(quote.GetStringBytes("outAmount")), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	otherAmountThreshold, err := strconv.ParseUint(string(quote.GetStringBytes("otherAmountThreshold")), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Quote{
+		InAmount:             inAmount,
+		OutAmount:            outAmount,
+		OtherAmountThreshold: otherAmountThreshold,
+		Raw:                  body,
+	}, nil
+}
+
+// GetSwapInstructions token accounts are expected to exist, sol is not wrapped/unwrapped
+func (c *JupiterClient) GetSwapInstructions(quote *Quote, user solana.PublicKey) (*SwapInstructions, error) {
+	body := make([]byte, 0, len(quote.Raw)+128)
+	body = append(body, `{"quoteResponse":`...)
+	body = append(body, quote.Raw...)
+	body = append(body, `,"userPublicKey":"`+user.String()+`","wrapAndUnwrapSol":false}`...)
+
+	resp, err := http.Post(c.Url+"/swap-instructions", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	bodyResp, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("swap instructions failed: %s", bodyResp)
+	}
+
+	value, err := c.parser.ParseBytes(bodyResp)
+	if err != nil {
+		return nil, err
+	}
+
+	swapIx, err := parseInstruction(value.Get("swapInstruction"))
+	if err != nil {
+		return nil, err
+	}
+	res := &SwapInstructions{
+		SwapInstruction: swapIx,
+	}
+	for _, setupIxValue := range value.GetArray("setupInstructions") {
+		setupIx, err := parseInstruction(setupIxValue)
+		if err != nil {
+			return nil, err
+		}
+		res.SetupInstructions = append(res.SetupInstructions, setupIx)
+	}
+	if cleanupIxValue := value.Get("cleanupInstruction"); cleanupIxValue != nil && cleanupIxValue.Type() == fastjson.TypeObject {
+		res.CleanupInstruction, err = parseInstruction(cleanupIxValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, table := range value.GetArray("addressLookupTableAddresses") {
+		tablePK, err := solana.PublicKeyFromBase58(string(table.GetStringBytes()))
+		if err != nil {
+			return nil, err
+		}
+		res.AddressLookupTables = append(res.AddressLookupTables, tablePK)
+	}
+
+	return res, nil
+}
+
+func parseInstruction(value *fastjson.Value) (solana.Instruction, error) {
+	if value == nil {
+		return nil, errors.New("missing instruction")
+	}
+	programId, err := solana.PublicKeyFromBase58(string(value.GetStringBytes("programId")))
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(string(value.GetStringBytes("data")))
+	if err != nil {
+		return nil, err
+	}
+	accountsValue := value.GetArray("accounts")
+	accounts := make(solana.AccountMetaSlice, 0, len(accountsValue))
+	for _, accountValue := range accountsValue {
+		pubkey, err := solana.PublicKeyFromBase58(string(accountValue.GetStringBytes("pubkey")))
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, solana.NewAccountMeta(pubkey, accountValue.GetBool("isWritable"), accountValue.GetBool("isSigner")))
+	}
+	return solana.NewInstruction(programId, accounts, data), nil
+}
@@ -18,14 +18,21 @@ const (
 	MarginRequirementTypeEquity
 )
 
+// MarginfiAccountDiscriminator sha256("account:MarginfiAccount")[:8]
+var MarginfiAccountDiscriminator = [...]byte{0x43, 0xb2, 0x82, 0x6d, 0x7e, 0x72, 0x1c, 0x2a}
+
+// MarginfiAccountSize discriminator + group + authority + balances + lending account padding u64x8 + flags + padding u64x63
+const MarginfiAccountSize = 8 + 32 + 32 + MaxBalances*104 + 8*8 + 8 + 63*8
+
 type MarginfiAccount struct {
-	// Group          solana.PublicKey
+	Group          solana.PublicKey
 	Authority      solana.PublicKey
 	LendingAccount LendingAccount
 	// AccountFlags   uint64
 }
 
 func ParseMarginfiAccount(data []byte) *MarginfiAccount {
+	group := solana.PublicKey(data[8:40])
 	authority := solana.PublicKey(data[40:72])
 	lendingAccountData := data[72:]
 	var lendingAccount LendingAccount
@@ -45,6 +52,7 @@ func ParseMarginfiAccount(data []byte) *MarginfiAccount {
 	}
 
 	return &MarginfiAccount{
+		Group:          group,
 		Authority:      authority,
 		LendingAccount: lendingAccount,
 		// AccountFlags:   binary.LittleEndian.Uint64(data[1608:1616]),
@@ -52,7 +60,8 @@ func ParseMarginfiAccount(data []byte) *MarginfiAccount {
 }
 
 func (m *MarginfiAccount) CanBeLiquidated(client *Client) (canBeLiquidated bool, assets fixed.I80F48, liabilities fixed.I80F48) {
-	assets, liabilities = m.ComputeHealthComponents(client.Banks, client.OraclePrices, MarginRequirementTypeMaintenance)
+	banks, oraclePrices := client.Snapshot()
+	assets, liabilities = m.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeMaintenance)
 	canBeLiquidated = assets.LessThan(liabilities)
 	return canBeLiquidated, assets, liabilities
 }
@@ -85,3 +94,104 @@ func (m *MarginfiAccount) ComputeHealthComponents(
 	}
 	return
 }
+
+func (m *MarginfiAccount) GetBalance(bankPK solana.PublicKey) *Balance {
+	for i := range m.LendingAccount.Balances {
+		balance := &m.LendingAccount.Balances[i]
+		if balance.Active && balance.BankPK == bankPK {
+			return balance
+		}
+	}
+	return nil
+}
+
+// ComputeFreeCollateral is initial assets minus initial liabilities clamped at zero, value is in USD
+func (m *MarginfiAccount) ComputeFreeCollateral(banks BankMap, oraclePrices OraclePriceMap) fixed.I80F48 {
+	assets, liabilities := m.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeInitial)
+	if assets.LessThan(liabilities) {
+		return fixed.I80F48{}
+	}
+	return assets.Sub(liabilities)
+}
+
+// ComputeMaxBorrowForBank returns max amount of bank tokens (in native units) that can be borrowed keeping initial health
+func (m *MarginfiAccount) ComputeMaxBorrowForBank(banks BankMap, oraclePrices OraclePriceMap, bankPK solana.PublicKey) fixed.I80F48 {
+	bank := banks[bankPK]
+	oraclePrice := oraclePrices[bankPK]
+	if bank == nil || oraclePrice == nil {
+		return fixed.I80F48{}
+	}
+
+	var assetShares fixed.I80F48
+	if balance := m.GetBalance(bankPK); balance != nil {
+		assetShares = balance.AssetShares
+	}
+
+	freeCollateral := m.ComputeFreeCollateral(banks, oraclePrices)
+	untiedCollateralForBank := bank.ComputeAssetUsdValue(oraclePrice, assetShares, MarginRequirementTypeInitial, PriceBiasLowest)
+	if freeCollateral.LessThan(untiedCollateralForBank) {
+		untiedCollateralForBank = freeCollateral
+	}
+
+	priceLowestBias := GetPrice(oraclePrice, PriceBiasLowest, true)
+	priceHighestBias := GetPrice(oraclePrice, PriceBiasHighest, true)
+	assetWeight := bank.GetAssetWeight(MarginRequirementTypeInitial, oraclePrice)
+	liabilityWeight := bank.GetLiabilityWeight(MarginRequirementTypeInitial)
+	scale := fixed.I80f48Pow10[bank.MintDecimals]
+
+	liabilityDivisor := priceHighestBias.Mul(liabilityWeight)
+	if liabilityDivisor.IsZero() {
+		return fixed.I80F48{}
+	}
+	borrowable := freeCollateral.Sub(untiedCollateralForBank).Div(liabilityDivisor).Mul(scale)
+
+	// collateral of the same bank is withdrawn first, if it doesn't count as collateral it's withdrawn entirely
+	assetDivisor := priceLowestBias.Mul(assetWeight)
+	if assetDivisor.IsZero() {
+		return bank.GetAssetQuantity(assetShares).Add(borrowable)
+	}
+	return untiedCollateralForBank.Div(assetDivisor).Mul(scale).Add(borrowable)
+}
+
+// ComputeMaxWithdrawForBank returns max amount of bank tokens (in native units) that can be withdrawn keeping initial health
+func (m *MarginfiAccount) ComputeMaxWithdrawForBank(banks BankMap, oraclePrices OraclePriceMap, bankPK solana.PublicKey) fixed.I80F48 {
+	bank := banks[bankPK]
+	oraclePrice := oraclePrices[bankPK]
+	balance := m.GetBalance(bankPK)
+	if bank == nil || oraclePrice == nil || balance == nil {
+		return fixed.I80F48{}
+	}
+
+	entireBalance := bank.GetAssetQuantity(balance.AssetShares)
+	freeCollateral := m.ComputeFreeCollateral(banks, oraclePrices)
+	initCollateralForBank := bank.ComputeAssetUsdValue(oraclePrice, balance.AssetShares, MarginRequirementTypeInitial, PriceBiasLowest)
+	_, liabilitiesInit := m.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeInitial)
+
+	if bank.Config.RiskTier == RiskTierIsolated || initCollateralForBank.LessThanOrEqual(freeCollateral) || liabilitiesInit.IsZero() {
+		return entireBalance
+	}
+
+	assetDivisor := GetPrice(oraclePrice, PriceBiasLowest, true).Mul(bank.GetAssetWeight(MarginRequirementTypeInitial, oraclePrice))
+	if assetDivisor.IsZero() {
+		return entireBalance
+	}
+	maxWithdraw := freeCollateral.Div(assetDivisor).Mul(fixed.I80f48Pow10[bank.MintDecimals])
+	if entireBalance.LessThan(maxWithdraw) {
+		return entireBalance
+	}
+	return maxWithdraw
+}
+
+// WithoutBanks returns a copy of the account with balances of given banks closed
+func (m *MarginfiAccount) WithoutBanks(bankPKs ...solana.PublicKey) *MarginfiAccount {
+	projected := *m
+	for i := range projected.LendingAccount.Balances {
+		balance := &projected.LendingAccount.Balances[i]
+		for _, bankPK := range bankPKs {
+			if balance.Active && balance.BankPK == bankPK {
+				*balance = Balance{}
+			}
+		}
+	}
+	return &projected
+}
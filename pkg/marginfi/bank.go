@@ -2,9 +2,9 @@ package marginfi
 
 import (
 	"encoding/binary"
-	"github.com/Pilatuz/bigz"
 	"github.com/gagliardetto/solana-go"
 	"jito-bot/pkg/fixed"
+	"jito-bot/pkg/pyth"
 )
 
 type BankOperationalState uint8
@@ -21,6 +21,17 @@ const (
 	OracleSetupNone OracleSetup = iota
 	OracleSetupPyth
 	OracleSetupSwitchboardV2
+	// OracleSetupPythPushOracle oracle key is pyth feed id, price is in push oracle PriceUpdateV2 account
+	OracleSetupPythPushOracle
+	OracleSetupSwitchboardPull
+)
+
+type RiskTier uint8
+
+const (
+	RiskTierCollateral RiskTier = iota
+	// RiskTierIsolated assets don't count towards collateral, can only be borrowed in isolation
+	RiskTierIsolated
 )
 
 type BankConfig struct {
@@ -32,16 +43,8 @@ type BankConfig struct {
 
 	DepositLimit uint64
 
-	// InterestRateConfig struct {
-	// 	OptimalUtilizationRate fixed.I80F48
-	// 	PlateauInteresetRate   fixed.I80F48
-	// 	MaxInterestRate        fixed.I80F48
-	// 	InsuranceFeeFixedApr   fixed.I80F48
-	// 	InsuranceIrFee         fixed.I80F48
-	// 	ProtocolFixedFeeApr    fixed.I80F48
-	// 	ProtocolIrFee          fixed.I80F48
-	// 	// padding u128x8
-	// } // size 128*7+128*8=1920/8=240 bytes
+	InterestRateConfig InterestRateConfig
+
 	OperationalState BankOperationalState
 
 	OracleSetup OracleSetup
@@ -49,6 +52,12 @@ type BankConfig struct {
 	// padding 6
 
 	BorrowLimit uint64
+
+	RiskTier RiskTier
+	// padding 7
+	TotalAssetValueInitLimit uint64
+	OracleMaxAge             uint16
+	// padding 38
 }
 
 type Bank struct {
@@ -120,7 +129,7 @@ func ParseBank(data []byte) *Bank {
 
 			DepositLimit: binary.LittleEndian.Uint64(data[352:360]),
 
-			// InterestRateConfig padding 240
+			InterestRateConfig: ParseInterestRateConfig(data[360:600]),
 
 			OperationalState: BankOperationalState(data[600]),
 			OracleSetup:      OracleSetup(data[601]),
@@ -133,10 +142,27 @@ func ParseBank(data []byte) *Bank {
 			},
 			// padding 6
 			BorrowLimit: binary.LittleEndian.Uint64(data[768:776]),
+
+			RiskTier: RiskTier(data[776]),
+			// padding 7
+			TotalAssetValueInitLimit: binary.LittleEndian.Uint64(data[784:792]),
+			OracleMaxAge:             binary.LittleEndian.Uint16(data[792:794]),
 		},
 	}
 }
 
+// OracleAccount is the account holding bank's price, for push oracle it's derived from the feed id (shard 0)
+func (b *Bank) OracleAccount() solana.PublicKey {
+	if b.Config.OracleSetup != OracleSetupPythPushOracle {
+		return b.Config.OracleKeys[0]
+	}
+	address, err := pyth.FindPushOraclePriceFeedAddress(0, b.Config.OracleKeys[0])
+	if err != nil {
+		return b.Config.OracleKeys[0]
+	}
+	return address
+}
+
 func (b *Bank) GetAssetQuantity(assetShares fixed.I80F48) fixed.I80F48 {
 	return assetShares.Mul(b.AssetShareValue)
 }
@@ -146,25 +172,41 @@ func (b *Bank) GetLiabilityQuantity(liabilityShares fixed.I80F48) fixed.I80F48 {
 }
 
 func (b *Bank) GetAssetShares(assetQuantity fixed.I80F48) fixed.I80F48 {
-	return assetQuantity.Mul(b.AssetShareValue)
+	return assetQuantity.Div(b.AssetShareValue)
 }
 
 func (b *Bank) GetLiabilityShares(liabilityQuantity fixed.I80F48) fixed.I80F48 {
-	return liabilityQuantity.Mul(b.LiabilityShareValue)
+	return liabilityQuantity.Div(b.LiabilityShareValue)
 }
 
-func (b *Bank) GetAssetWeight(reqType MarginRequirementType) fixed.I80F48 {
+func (b *Bank) GetAssetWeight(reqType MarginRequirementType, oraclePrice *OraclePrice) fixed.I80F48 {
+	if b.Config.RiskTier == RiskTierIsolated {
+		return fixed.I80F48{}
+	}
 	switch reqType {
+	case MarginRequirementTypeInitial:
+		return b.getAssetWeightInit(oraclePrice)
 	case MarginRequirementTypeMaintenance:
 		return b.Config.AssetWeightMaint
-	case MarginRequirementTypeInitial:
-		panic("not implemented")
 	case MarginRequirementTypeEquity:
-		panic("not implemented")
+		return fixed.I80f48One
 	}
 	panic("unreachable")
 }
 
+// getAssetWeightInit discounts init asset weight once the bank total deposits are above TotalAssetValueInitLimit
+func (b *Bank) getAssetWeightInit(oraclePrice *OraclePrice) fixed.I80F48 {
+	if b.Config.TotalAssetValueInitLimit == 0 {
+		return b.Config.AssetWeightInit
+	}
+	totalAssetValue := b.ComputeUsdValue(oraclePrice, b.GetAssetQuantity(b.TotalAssetShares), PriceBiasLowest, true, fixed.I80f48One, true)
+	totalAssetValueInitLimit := fixed.I80f48One.Mul64(b.Config.TotalAssetValueInitLimit)
+	if !totalAssetValueInitLimit.LessThan(totalAssetValue) {
+		return b.Config.AssetWeightInit
+	}
+	return b.Config.AssetWeightInit.Mul(totalAssetValueInitLimit.Div(totalAssetValue))
+}
+
 func (b *Bank) GetLiabilityWeight(reqType MarginRequirementType) fixed.I80F48 {
 	switch reqType {
 	case MarginRequirementTypeInitial:
@@ -172,8 +214,7 @@ func (b *Bank) GetLiabilityWeight(reqType MarginRequirementType) fixed.I80F48 {
 	case MarginRequirementTypeMaintenance:
 		return b.Config.LiabilityWeightMaint
 	case MarginRequirementTypeEquity:
-		return fixed.I80F48{bigz.One256()}
-		//return fixed.I80F48{num.U128FromUint(1)}
+		return fixed.I80f48One
 	}
 	panic("unreachable")
 }
@@ -182,8 +223,8 @@ func (b *Bank) ComputeAssetUsdValue(
 	oraclePrice *OraclePrice, assetShares fixed.I80F48, reqType MarginRequirementType, bias PriceBias,
 ) fixed.I80F48 {
 	assetQuantity := b.GetAssetQuantity(assetShares)
-	assetWeight := b.GetAssetWeight(reqType)
-	isWeighted := reqType == MarginRequirementTypeInitial
+	assetWeight := b.GetAssetWeight(reqType, oraclePrice)
+	isWeighted := IsWeightedPrice(reqType)
 	return b.ComputeUsdValue(oraclePrice, assetQuantity, bias, isWeighted, assetWeight, true)
 }
 
@@ -192,18 +233,16 @@ func (b *Bank) ComputeLiabilityUsdValue(
 ) fixed.I80F48 {
 	liabilityQuantity := b.GetLiabilityQuantity(liabilityShares)
 	liabilityWeight := b.GetLiabilityWeight(reqType)
-	isWeighted := reqType == MarginRequirementTypeInitial
+	isWeighted := IsWeightedPrice(reqType)
 	return b.ComputeUsdValue(oraclePrice, liabilityQuantity, bias, isWeighted, liabilityWeight, true)
 }
 
+// ComputeUsdValue isWeighted picks time weighted price, weight is always applied
 func (b *Bank) ComputeUsdValue(
 	oraclePrice *OraclePrice, quantity fixed.I80F48, bias PriceBias, isWeighted bool, weight fixed.I80F48, scaleToBase bool,
 ) (res fixed.I80F48) {
 	price := GetPrice(oraclePrice, bias, isWeighted)
-	res = quantity.Mul(price)
-	if isWeighted {
-		res = res.Mul(weight)
-	}
+	res = quantity.Mul(weight).Mul(price)
 	if scaleToBase {
 		res = res.Div(fixed.I80f48Pow10[b.MintDecimals])
 	}
@@ -0,0 +1,70 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"math"
+	"testing"
+)
+
+func testBankAndPrice() (*Bank, *OraclePrice) {
+	bank := &Bank{
+		MintDecimals:        6,
+		AssetShareValue:     fixed.I80f48One,
+		LiabilityShareValue: fixed.I80f48One,
+		TotalAssetShares:    fixed.MustI80F48FromFloat64(1_000_000_000), // 1000 tokens
+		Config: BankConfig{
+			AssetWeightInit:          fixed.MustI80F48FromFloat64(0.8),
+			AssetWeightMaint:         fixed.MustI80F48FromFloat64(0.9),
+			LiabilityWeightInit:      fixed.MustI80F48FromFloat64(1.25),
+			LiabilityWeightMaint:     fixed.MustI80F48FromFloat64(1.1),
+			TotalAssetValueInitLimit: 1000,
+		},
+	}
+	price := &OraclePrice{
+		PriceRealtime: NewPriceWithConfidence(2, 0),
+		PriceWeighted: NewPriceWithConfidence(4, 0),
+	}
+	return bank, price
+}
+
+func TestComputeAssetUsdValue(t *testing.T) {
+	bank, price := testBankAndPrice()
+	shares := fixed.MustI80F48FromFloat64(10_000_000) // 10 tokens
+
+	cases := []struct {
+		reqType  MarginRequirementType
+		expected float64
+	}{
+		// 10 * 2 (realtime) * 0.9
+		{MarginRequirementTypeMaintenance, 18},
+		// bank holds 4000$ > 1000$ limit, weight is discounted to 0.8 * 1000 / 4000, 10 * 4 (ema) * 0.2
+		{MarginRequirementTypeInitial, 8},
+		// 10 * 2 (realtime) * 1
+		{MarginRequirementTypeEquity, 20},
+	}
+	for _, c := range cases {
+		value := bank.ComputeAssetUsdValue(price, shares, c.reqType, PriceBiasLowest)
+		if math.Abs(value.AsFloat64()-c.expected) > 1e-9 {
+			t.Errorf("requirement %v: got %v, expected %v", c.reqType, value.AsFloat64(), c.expected)
+		}
+	}
+
+	bank.Config.RiskTier = RiskTierIsolated
+	if value := bank.ComputeAssetUsdValue(price, shares, MarginRequirementTypeMaintenance, PriceBiasLowest); !value.IsZero() {
+		t.Errorf("isolated asset must not count as collateral, got %v", value.AsFloat64())
+	}
+}
+
+func TestComputeLiabilityUsdValue(t *testing.T) {
+	bank, price := testBankAndPrice()
+	shares := fixed.MustI80F48FromFloat64(10_000_000) // 10 tokens
+
+	maint := bank.ComputeLiabilityUsdValue(price, shares, MarginRequirementTypeMaintenance, PriceBiasHighest)
+	if math.Abs(maint.AsFloat64()-22) > 1e-9 {
+		t.Errorf("maintenance: got %v", maint.AsFloat64())
+	}
+	equity := bank.ComputeLiabilityUsdValue(price, shares, MarginRequirementTypeEquity, PriceBiasHighest)
+	if math.Abs(equity.AsFloat64()-20) > 1e-9 {
+		t.Errorf("equity: got %v", equity.AsFloat64())
+	}
+}
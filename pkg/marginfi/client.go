@@ -3,70 +3,251 @@ package marginfi
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+var ProgramAddress = solana.MustPublicKeyFromBase58("MFv2hWf31Z9kbCa1snEPYctwafyhdvnV7FZnsebVacA")
 var GroupAddress = solana.MustPublicKeyFromBase58("4qp6Fx6tnZkY5Wropq9wUYgtFxXKwE6viZxFHg3rdAG8")
 
+var (
+	BankMetadataUrl = "https://storage.googleapis.com/mrgn-public/mrgn-bank-metadata-cache.json"
+
+	// BankDiscriminator sha256("account:Bank")[:8]
+	BankDiscriminator = [...]byte{0x8e, 0x31, 0xa6, 0xf2, 0x32, 0x42, 0x61, 0xbc}
+)
+
+const (
+	// bankGroupOffset discriminator + mint + decimals
+	bankGroupOffset = 8 + 32 + 1
+	// maxMultipleAccounts getMultipleAccounts limit
+	maxMultipleAccounts = 100
+)
+
 type BankMap map[solana.PublicKey]*Bank
 type OraclePriceMap map[solana.PublicKey]*OraclePrice
 
+// Client holds banks of a group discovered from chain and their oracle prices, Refresh reloads both.
+// Maps are replaced on refresh and never mutated, read them through Snapshot
 type Client struct {
-	Banks        BankMap
-	OraclePrices OraclePriceMap
+	connection *rpc.Client
+	group      solana.PublicKey
+
+	mu           sync.RWMutex
+	banks        BankMap
+	oraclePrices OraclePriceMap
+	// oracleErrors why banks have no price in oraclePrices, e.g. stale or halted pyth price
+	oracleErrors map[solana.PublicKey]error
+	// metadata is optional token info, see LoadMetadata
+	metadata map[solana.PublicKey]BankMetadata
 }
 
 func NewClient(connection *rpc.Client) (*Client, error) {
-	banksMetadata, err := loadBankMetadatas()
-	if err != nil {
+	c := &Client{
+		connection: connection,
+		group:      GroupAddress,
+		metadata:   make(map[solana.PublicKey]BankMetadata),
+	}
+	if err := c.Refresh(); err != nil {
 		return nil, err
 	}
+	return c, nil
+}
 
-	allBanks := make([]solana.PublicKey, 0, len(banksMetadata))
-	for bankKey := range banksMetadata {
-		allBanks = append(allBanks, bankKey)
+// Refresh reloads banks of the group and prices of their oracles
+func (c *Client) Refresh() error {
+	banks, err := LoadBanks(c.connection, c.group)
+	if err != nil {
+		return err
 	}
-
-	banksRes, err := connection.GetMultipleAccounts(context.Background(), allBanks...)
+	oraclePrices, oracleErrors, err := LoadOraclePrices(c.connection, banks)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	bankKeys := make([]solana.PublicKey, 0, len(allBanks))
-	banksMap := make(map[solana.PublicKey]*Bank, len(allBanks))
-	for i, bankRaw := range banksRes.Value {
-		bank := ParseBank(bankRaw.Data.GetBinary())
-		// TODO add support for banks that uses switchboard as oracle
-		if bank.Config.OracleSetup != OracleSetupPyth {
-			continue
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.banks = banks
+	c.oraclePrices = oraclePrices
+	c.oracleErrors = oracleErrors
+	return nil
+}
+
+// RefreshPeriodically calls Refresh every interval until ctx is done, failed refreshes keep the previous state
+func (c *Client) RefreshPeriodically(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Snapshot returns banks and prices of the last refresh
+func (c *Client) Snapshot() (BankMap, OraclePriceMap) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.banks, c.oraclePrices
+}
+
+// OracleError tells why the bank has no price in the last refresh, nil when it has one
+func (c *Client) OracleError(bankPK solana.PublicKey) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.oracleErrors[bankPK]
+}
+
+// BanksByOracle returns banks priced by the oracle account
+func (c *Client) BanksByOracle(oracle solana.PublicKey) []solana.PublicKey {
+	banks, _ := c.Snapshot()
+	return banks.ByOracle(oracle)
+}
+
+// ByOracle returns banks priced by the oracle account
+func (m BankMap) ByOracle(oracle solana.PublicKey) []solana.PublicKey {
+	banks := make([]solana.PublicKey, 0, 1)
+	for bankPK, bank := range m {
+		if bank.OracleAccount() == oracle {
+			banks = append(banks, bankPK)
 		}
-		banksMap[allBanks[i]] = bank
-		bankKeys = append(bankKeys, allBanks[i])
+	}
+	return banks
+}
+
+// LoadBanks discovers every bank of the group with getProgramAccounts
+func LoadBanks(connection *rpc.Client, group solana.PublicKey) (BankMap, error) {
+	gpa, err := connection.GetProgramAccountsWithOpts(context.Background(), ProgramAddress, &rpc.GetProgramAccountsOpts{
+		Filters: []rpc.RPCFilter{
+			{
+				Memcmp: &rpc.RPCFilterMemcmp{
+					Offset: 0,
+					Bytes:  BankDiscriminator[:],
+				},
+			},
+			{
+				Memcmp: &rpc.RPCFilterMemcmp{
+					Offset: bankGroupOffset,
+					Bytes:  group.Bytes(),
+				},
+			},
+		}})
+	if err != nil {
+		return nil, err
 	}
 
-	priceFeedsKeys := make([]solana.PublicKey, 0, len(bankKeys))
-	for _, bank := range bankKeys {
-		bank := banksMap[bank]
-		priceFeedsKeys = append(priceFeedsKeys, bank.Config.OracleKeys[0])
+	banks := make(BankMap, len(gpa))
+	for _, gpaAcc := range gpa {
+		banks[gpaAcc.Pubkey] = ParseBank(gpaAcc.Account.Data.GetBinary())
 	}
+	return banks, nil
+}
 
-	priceFeedsRes, err := connection.GetMultipleAccounts(context.Background(), priceFeedsKeys...)
+// LoadAccounts returns every marginfi account of the group
+func LoadAccounts(connection *rpc.Client, group solana.PublicKey) (map[solana.PublicKey]*MarginfiAccount, error) {
+	return loadAccounts(connection, rpc.RPCFilter{
+		Memcmp: &rpc.RPCFilterMemcmp{
+			Offset: 8,
+			Bytes:  group.Bytes(),
+		},
+	})
+}
+
+// LoadAccountsByAuthority returns marginfi accounts of the group owned by authority
+func LoadAccountsByAuthority(connection *rpc.Client, group solana.PublicKey, authority solana.PublicKey) (map[solana.PublicKey]*MarginfiAccount, error) {
+	return loadAccounts(connection,
+		rpc.RPCFilter{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: 8,
+				Bytes:  group.Bytes(),
+			},
+		},
+		rpc.RPCFilter{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: 8 + 32,
+				Bytes:  authority.Bytes(),
+			},
+		})
+}
+
+// loadAccounts only queries marginfi accounts, other program accounts matching the filters are skipped
+func loadAccounts(connection *rpc.Client, filters ...rpc.RPCFilter) (map[solana.PublicKey]*MarginfiAccount, error) {
+	gpa, err := connection.GetProgramAccountsWithOpts(context.Background(), ProgramAddress, &rpc.GetProgramAccountsOpts{
+		Filters: append([]rpc.RPCFilter{
+			{DataSize: MarginfiAccountSize},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: MarginfiAccountDiscriminator[:]}},
+		}, filters...),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	priceFeedsMap := make(map[solana.PublicKey]*OraclePrice, len(bankKeys))
-	for i, priceFeedRaw := range priceFeedsRes.Value {
-		priceFeedsMap[bankKeys[i]] = ParseOraclePrice(OracleSetupPyth, priceFeedRaw.Data.GetBinary())
+	accounts := make(map[solana.PublicKey]*MarginfiAccount, len(gpa))
+	for _, gpaAcc := range gpa {
+		data := gpaAcc.Account.Data.GetBinary()
+		if !isMarginfiAccount(data) {
+			continue
+		}
+		accounts[gpaAcc.Pubkey] = ParseMarginfiAccount(data)
+	}
+	return accounts, nil
+}
+
+// LoadOraclePrices fetches oracles of the banks, banks with unsupported or invalid oracles get no price
+// and accounts holding them are not evaluated, the reason is returned per bank
+func LoadOraclePrices(connection *rpc.Client, banks BankMap) (OraclePriceMap, map[solana.PublicKey]error, error) {
+	bankKeys := make([]solana.PublicKey, 0, len(banks))
+	oracleKeys := make([]solana.PublicKey, 0, len(banks))
+	for bankPK, bank := range banks {
+		switch bank.Config.OracleSetup {
+		case OracleSetupPyth, OracleSetupSwitchboardV2, OracleSetupPythPushOracle:
+		default:
+			continue
+		}
+		bankKeys = append(bankKeys, bankPK)
+		oracleKeys = append(oracleKeys, bank.OracleAccount())
 	}
 
-	return &Client{
-		Banks:        banksMap,
-		OraclePrices: priceFeedsMap,
-	}, nil
+	oraclePrices := make(OraclePriceMap, len(bankKeys))
+	oracleErrors := make(map[solana.PublicKey]error)
+	now := time.Now().Unix()
+	for start := 0; start < len(oracleKeys); start += maxMultipleAccounts {
+		end := min(start+maxMultipleAccounts, len(oracleKeys))
+		res, err := connection.GetMultipleAccounts(context.Background(), oracleKeys[start:end]...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, oracleRaw := range res.Value {
+			bankPK := bankKeys[start+i]
+			if oracleRaw == nil {
+				oracleErrors[bankPK] = rpc.ErrNotFound
+				continue
+			}
+			oraclePrice, err := ParseBankOraclePrice(banks[bankPK], oracleRaw.Data.GetBinary(), res.Context.Slot, now)
+			if err != nil {
+				oracleErrors[bankPK] = err
+				continue
+			}
+			oraclePrices[bankPK] = oraclePrice
+		}
+	}
+	for bankPK := range banks {
+		if _, ok := oraclePrices[bankPK]; !ok && oracleErrors[bankPK] == nil {
+			oracleErrors[bankPK] = ErrUnsupportedOracleSetup
+		}
+	}
+	return oraclePrices, oracleErrors, nil
 }
 
 type BankMetadata struct {
@@ -75,18 +256,60 @@ type BankMetadata struct {
 	TokenSymbol  string
 }
 
-func loadBankMetadatas() (map[solana.PublicKey]BankMetadata, error) {
-	res, err := http.Get("https://storage.googleapis.com/mrgn-public/mrgn-bank-metadata-cache.json")
+// LoadMetadata enriches banks with token names, metadata is fetched from BankMetadataUrl and cached at cachePath,
+// the cache is used when the url is unreachable. Banks work without metadata so callers may ignore the error.
+func (c *Client) LoadMetadata(cachePath string) error {
+	metadata, err := LoadBankMetadatas(cachePath)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metadata = metadata
+	return nil
+}
+
+// Metadata returns token info of the last LoadMetadata, the map is replaced on load and must not be modified
+func (c *Client) Metadata() map[solana.PublicKey]BankMetadata {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metadata
+}
+
+// BankSymbol returns token symbol of the bank or its mint when metadata is unknown
+func (c *Client) BankSymbol(bankPK solana.PublicKey) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if metadata, ok := c.metadata[bankPK]; ok && metadata.TokenSymbol != "" {
+		return metadata.TokenSymbol
+	}
+	if bank, ok := c.banks[bankPK]; ok {
+		return bank.Mint.String()
+	}
+	return bankPK.String()
+}
+
+func LoadBankMetadatas(cachePath string) (map[solana.PublicKey]BankMetadata, error) {
+	raw, err := fetchBankMetadatas()
+	if err != nil {
+		if cachePath == "" {
+			return nil, err
+		}
+		raw, err = os.ReadFile(cachePath)
+		if err != nil {
+			return nil, err
+		}
+	} else if cachePath != "" {
+		// cache is best effort, fresh metadata is still usable
+		_ = os.WriteFile(cachePath, raw, 0o644)
 	}
-	defer res.Body.Close()
 
 	var bankMetadatas []struct {
 		BankAddress solana.PublicKey
 		BankMetadata
 	}
-	if err := json.NewDecoder(res.Body).Decode(&bankMetadatas); err != nil {
+	if err := json.Unmarshal(raw, &bankMetadatas); err != nil {
 		return nil, err
 	}
 
@@ -96,3 +319,20 @@ func loadBankMetadatas() (map[solana.PublicKey]BankMetadata, error) {
 	}
 	return bankMetadatasMap, nil
 }
+
+func fetchBankMetadatas() ([]byte, error) {
+	res, err := http.Get(BankMetadataUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bank metadata: unexpected status %d", res.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
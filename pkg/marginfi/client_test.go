@@ -0,0 +1,77 @@
+package marginfi
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestLoadBankMetadatasCache(t *testing.T) {
+	bankPK := solana.NewWallet().PublicKey()
+	body := `[{"bankAddress":"` + bankPK.String() + `","tokenAddress":"So11111111111111111111111111111111111111112","tokenName":"Wrapped SOL","tokenSymbol":"SOL"}]`
+
+	online := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !online {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	defaultUrl := BankMetadataUrl
+	BankMetadataUrl = server.URL
+	defer func() { BankMetadataUrl = defaultUrl }()
+
+	cachePath := filepath.Join(t.TempDir(), "bank-metadata.json")
+	metadata, err := LoadBankMetadatas(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata[bankPK].TokenSymbol != "SOL" {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+
+	online = false
+	metadata, err = LoadBankMetadatas(cachePath)
+	if err != nil {
+		t.Fatalf("cache should be used when url is down: %v", err)
+	}
+	if metadata[bankPK].TokenSymbol != "SOL" {
+		t.Fatalf("unexpected cached metadata %+v", metadata)
+	}
+
+	if _, err := LoadBankMetadatas(""); err == nil {
+		t.Fatal("expected error without cache")
+	}
+}
+
+func TestLoadAccountsSkipsForeignAccounts(t *testing.T) {
+	validPK, shortPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	valid := make([]byte, MarginfiAccountSize)
+	copy(valid, MarginfiAccountDiscriminator[:])
+	copy(valid[8:], GroupAddress[:])
+
+	account := func(pubkey solana.PublicKey, data []byte) string {
+		return `{"pubkey":"` + pubkey.String() + `","account":{"data":["` + base64.StdEncoding.EncodeToString(data) +
+			`","base64"],"executable":false,"lamports":1,"owner":"` + ProgramAddress.String() + `","rentEpoch":0}}`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[` + account(validPK, valid) + `,` + account(shortPK, valid[:72]) + `]}`))
+	}))
+	defer server.Close()
+
+	accounts, err := LoadAccounts(rpc.New(server.URL), GroupAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[validPK] == nil {
+		t.Fatalf("only the valid account should be loaded, got %v", accounts)
+	}
+}
@@ -0,0 +1,275 @@
+package marginfi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"jito-bot/pkg/fixed"
+)
+
+// AccountHealth is maintenance health of an account, kept up to date by AccountIndex
+type AccountHealth struct {
+	Assets      fixed.I80F48
+	Liabilities fixed.I80F48
+}
+
+func (h AccountHealth) IsLiquidatable() bool {
+	return h.Assets.LessThan(h.Liabilities)
+}
+
+// AccountIndex keeps every marginfi account of a group in memory, it is filled by Reconcile and kept current
+// by Subscribe. Accounts are also indexed by the banks they hold so price moves re-evaluate only affected accounts.
+type AccountIndex struct {
+	group solana.PublicKey
+
+	mu       sync.RWMutex
+	accounts map[solana.PublicKey]*MarginfiAccount
+	slots    map[solana.PublicKey]uint64
+	byBank   map[solana.PublicKey]map[solana.PublicKey]struct{}
+	health   map[solana.PublicKey]AccountHealth
+
+	banks        BankMap
+	oraclePrices OraclePriceMap
+}
+
+func NewAccountIndex(group solana.PublicKey) *AccountIndex {
+	return &AccountIndex{
+		group:    group,
+		accounts: make(map[solana.PublicKey]*MarginfiAccount),
+		slots:    make(map[solana.PublicKey]uint64),
+		byBank:   make(map[solana.PublicKey]map[solana.PublicKey]struct{}),
+		health:   make(map[solana.PublicKey]AccountHealth),
+	}
+}
+
+func (x *AccountIndex) groupFilters() []rpc.RPCFilter {
+	return []rpc.RPCFilter{
+		{DataSize: MarginfiAccountSize},
+		{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: MarginfiAccountDiscriminator[:]}},
+		{Memcmp: &rpc.RPCFilterMemcmp{Offset: 8, Bytes: x.group.Bytes()}},
+	}
+}
+
+// isMarginfiAccount guards ParseMarginfiAccount which doesn't check the data
+func isMarginfiAccount(data []byte) bool {
+	return len(data) >= MarginfiAccountSize && [8]byte(data[:8]) == MarginfiAccountDiscriminator
+}
+
+// Reconcile reloads all accounts of the group with getProgramAccounts, accounts missing from the response
+// and not updated since are dropped. Updates newer than the reconciliation slot are kept.
+func (x *AccountIndex) Reconcile(ctx context.Context, connection *rpc.Client) error {
+	slot, err := connection.GetSlot(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return err
+	}
+	gpa, err := connection.GetProgramAccountsWithOpts(ctx, ProgramAddress, &rpc.GetProgramAccountsOpts{
+		Commitment: rpc.CommitmentConfirmed,
+		Filters:    x.groupFilters(),
+	})
+	if err != nil {
+		return err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	seen := make(map[solana.PublicKey]struct{}, len(gpa))
+	for _, gpaAcc := range gpa {
+		data := gpaAcc.Account.Data.GetBinary()
+		if !isMarginfiAccount(data) {
+			continue
+		}
+		seen[gpaAcc.Pubkey] = struct{}{}
+		x.upsert(gpaAcc.Pubkey, ParseMarginfiAccount(data), slot)
+	}
+	for accountPK, accountSlot := range x.slots {
+		if _, ok := seen[accountPK]; !ok && accountSlot <= slot {
+			x.remove(accountPK)
+		}
+	}
+	return nil
+}
+
+// Subscribe streams account changes of the group until the subscription fails or ctx is done
+func (x *AccountIndex) Subscribe(ctx context.Context, wsClient *ws.Client) error {
+	sub, err := wsClient.ProgramSubscribeWithOpts(ProgramAddress, rpc.CommitmentConfirmed, solana.EncodingBase64, x.groupFilters())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res, err := sub.Recv()
+		if err != nil {
+			return err
+		}
+		x.Update(res.Value.Pubkey, res.Value.Account, res.Context.Slot)
+	}
+}
+
+// Update applies account state observed at slot, closed accounts are removed and stale updates ignored
+func (x *AccountIndex) Update(accountPK solana.PublicKey, account *rpc.Account, slot uint64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if slot < x.slots[accountPK] {
+		return
+	}
+	if account == nil || account.Lamports == 0 || !isMarginfiAccount(account.Data.GetBinary()) {
+		x.remove(accountPK)
+		return
+	}
+	x.upsert(accountPK, ParseMarginfiAccount(account.Data.GetBinary()), slot)
+}
+
+func (x *AccountIndex) upsert(accountPK solana.PublicKey, account *MarginfiAccount, slot uint64) {
+	if slot < x.slots[accountPK] {
+		return
+	}
+	x.unindex(accountPK)
+	x.accounts[accountPK] = account
+	x.slots[accountPK] = slot
+	for _, balance := range account.LendingAccount.Balances {
+		if !balance.Active {
+			continue
+		}
+		holders, ok := x.byBank[balance.BankPK]
+		if !ok {
+			holders = make(map[solana.PublicKey]struct{})
+			x.byBank[balance.BankPK] = holders
+		}
+		holders[accountPK] = struct{}{}
+	}
+	x.updateHealth(accountPK)
+}
+
+func (x *AccountIndex) remove(accountPK solana.PublicKey) {
+	x.unindex(accountPK)
+	delete(x.accounts, accountPK)
+	delete(x.slots, accountPK)
+	delete(x.health, accountPK)
+}
+
+func (x *AccountIndex) unindex(accountPK solana.PublicKey) {
+	account, ok := x.accounts[accountPK]
+	if !ok {
+		return
+	}
+	for _, balance := range account.LendingAccount.Balances {
+		if !balance.Active {
+			continue
+		}
+		delete(x.byBank[balance.BankPK], accountPK)
+		if len(x.byBank[balance.BankPK]) == 0 {
+			delete(x.byBank, balance.BankPK)
+		}
+	}
+}
+
+func (x *AccountIndex) updateHealth(accountPK solana.PublicKey) {
+	if x.banks == nil || x.oraclePrices == nil {
+		return
+	}
+	assets, liabilities := x.accounts[accountPK].ComputeHealthComponents(x.banks, x.oraclePrices, MarginRequirementTypeMaintenance)
+	x.health[accountPK] = AccountHealth{Assets: assets, Liabilities: liabilities}
+}
+
+// SetBanks replaces banks and prices and recomputes health of every account
+func (x *AccountIndex) SetBanks(banks BankMap, oraclePrices OraclePriceMap) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.banks = banks
+	// copied since SetPrices merges into it
+	x.oraclePrices = make(OraclePriceMap, len(oraclePrices))
+	for bankPK, price := range oraclePrices {
+		x.oraclePrices[bankPK] = price
+	}
+	for accountPK := range x.accounts {
+		x.updateHealth(accountPK)
+	}
+}
+
+// SetPrices merges prices of the changed banks and recomputes health only of accounts holding them,
+// affected accounts are returned
+func (x *AccountIndex) SetPrices(oraclePrices OraclePriceMap) []solana.PublicKey {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.oraclePrices == nil {
+		x.oraclePrices = make(OraclePriceMap, len(oraclePrices))
+	}
+	affected := make(map[solana.PublicKey]struct{})
+	for bankPK, price := range oraclePrices {
+		x.oraclePrices[bankPK] = price
+		for accountPK := range x.byBank[bankPK] {
+			affected[accountPK] = struct{}{}
+		}
+	}
+
+	accountPKs := make([]solana.PublicKey, 0, len(affected))
+	for accountPK := range affected {
+		x.updateHealth(accountPK)
+		accountPKs = append(accountPKs, accountPK)
+	}
+	return accountPKs
+}
+
+func (x *AccountIndex) Get(accountPK solana.PublicKey) (*MarginfiAccount, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	account, ok := x.accounts[accountPK]
+	return account, ok
+}
+
+func (x *AccountIndex) Health(accountPK solana.PublicKey) (AccountHealth, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	health, ok := x.health[accountPK]
+	return health, ok
+}
+
+// AccountsByBank returns accounts with an active balance in any of the banks
+func (x *AccountIndex) AccountsByBank(bankPKs ...solana.PublicKey) []solana.PublicKey {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	seen := make(map[solana.PublicKey]struct{})
+	accountPKs := make([]solana.PublicKey, 0)
+	for _, bankPK := range bankPKs {
+		for accountPK := range x.byBank[bankPK] {
+			if _, ok := seen[accountPK]; ok {
+				continue
+			}
+			seen[accountPK] = struct{}{}
+			accountPKs = append(accountPKs, accountPK)
+		}
+	}
+	return accountPKs
+}
+
+// Liquidatable returns accounts below maintenance according to the last known banks and prices
+func (x *AccountIndex) Liquidatable() []solana.PublicKey {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	accountPKs := make([]solana.PublicKey, 0)
+	for accountPK, health := range x.health {
+		if health.IsLiquidatable() {
+			accountPKs = append(accountPKs, accountPK)
+		}
+	}
+	return accountPKs
+}
+
+func (x *AccountIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.accounts)
+}
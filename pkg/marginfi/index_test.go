@@ -0,0 +1,116 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestAccountIndex(t *testing.T) {
+	solBankPK, usdcBankPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	banks := BankMap{
+		solBankPK: &Bank{
+			MintDecimals:        9,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(0.9),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+			},
+		},
+		usdcBankPK: &Bank{
+			MintDecimals:        6,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(1),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+			},
+		},
+	}
+
+	borrowerPK, lenderPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	borrower := &MarginfiAccount{}
+	// 1 SOL collateral, 70 USDC debt
+	borrower.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e9)}
+	borrower.LendingAccount.Balances[1] = Balance{Active: true, BankPK: usdcBankPK, LiabilityShares: fixed.MustI80F48FromFloat64(70e6)}
+	lender := &MarginfiAccount{}
+	lender.LendingAccount.Balances[0] = Balance{Active: true, BankPK: usdcBankPK, AssetShares: fixed.MustI80F48FromFloat64(100e6)}
+
+	index := NewAccountIndex(GroupAddress)
+	index.SetBanks(banks, OraclePriceMap{
+		solBankPK:  &OraclePrice{PriceRealtime: NewPriceWithConfidence(100, 0)},
+		usdcBankPK: &OraclePrice{PriceRealtime: NewPriceWithConfidence(1, 0)},
+	})
+	index.upsert(borrowerPK, borrower, 10)
+	index.upsert(lenderPK, lender, 10)
+
+	if got := index.AccountsByBank(solBankPK); len(got) != 1 || got[0] != borrowerPK {
+		t.Fatalf("unexpected sol bank holders %v", got)
+	}
+	if got := index.AccountsByBank(usdcBankPK, solBankPK); len(got) != 2 {
+		t.Fatalf("expected both accounts, got %v", got)
+	}
+	if len(index.Liquidatable()) != 0 {
+		t.Fatal("nothing should be liquidatable at 100$")
+	}
+
+	affected := index.SetPrices(OraclePriceMap{solBankPK: &OraclePrice{PriceRealtime: NewPriceWithConfidence(80, 0)}})
+	if len(affected) != 1 || affected[0] != borrowerPK {
+		t.Fatalf("only sol holders should be re-evaluated, got %v", affected)
+	}
+	if got := index.Liquidatable(); len(got) != 1 || got[0] != borrowerPK {
+		t.Fatalf("borrower should be liquidatable at 80$, got %v", got)
+	}
+
+	// stale update is ignored, newer one moves the account out of the sol bank
+	repaid := &MarginfiAccount{}
+	repaid.LendingAccount.Balances[0] = Balance{Active: true, BankPK: usdcBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e6)}
+	index.upsert(borrowerPK, repaid, 9)
+	if len(index.AccountsByBank(solBankPK)) != 1 {
+		t.Fatal("stale update must be ignored")
+	}
+	index.upsert(borrowerPK, repaid, 11)
+	if len(index.AccountsByBank(solBankPK)) != 0 {
+		t.Fatal("sol bank index should be empty after update")
+	}
+	if len(index.Liquidatable()) != 0 {
+		t.Fatal("health should be recomputed on update")
+	}
+
+	index.Update(lenderPK, nil, 12)
+	if _, ok := index.Get(lenderPK); ok || index.Len() != 1 {
+		t.Fatal("closed account should be removed")
+	}
+}
+
+func TestMarginfiAccountSizeLayout(t *testing.T) {
+	// 8 + size_of::<MarginfiAccount>() of the program, the dataSize filter matches nothing when this drifts
+	if MarginfiAccountSize != 2312 {
+		t.Fatalf("got %d, expected 2312", MarginfiAccountSize)
+	}
+}
+
+func TestAccountIndexUpdateRejectsForeignAccounts(t *testing.T) {
+	index := NewAccountIndex(GroupAddress)
+	accountPK := solana.NewWallet().PublicKey()
+
+	valid := make([]byte, MarginfiAccountSize)
+	copy(valid, MarginfiAccountDiscriminator[:])
+	copy(valid[8:], GroupAddress[:])
+	index.Update(accountPK, &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes(valid)}, 1)
+	if _, ok := index.Get(accountPK); !ok {
+		t.Fatal("valid account should be indexed")
+	}
+
+	foreign := make([]byte, MarginfiAccountSize)
+	copy(foreign[8:], GroupAddress[:])
+	for _, data := range [][]byte{foreign, valid[:40]} {
+		index.Update(accountPK, &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes(data)}, 2)
+		if _, ok := index.Get(accountPK); ok {
+			t.Fatal("foreign or truncated account should be dropped")
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package marginfi
+
+import (
+	"context"
+	"errors"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	liquidityVaultAuthoritySeed = []byte("liquidity_vault_auth")
+)
+
+var (
+	MarginfiAccountInitializeDiscriminator = [...]byte{
+		0x2b, 0x4e, 0x3d, 0xff, 0x94, 0x34, 0xf9, 0x9a,
+	}
+	LendingAccountLiquidateDiscriminator = [...]byte{
+		0xd6, 0xa9, 0x97, 0xd5, 0xfb, 0xa7, 0x56, 0xdb,
+	}
+	LendingAccountWithdrawDiscriminator = [...]byte{
+		0x24, 0x48, 0x4a, 0x13, 0xd2, 0xd2, 0xc0, 0xc0,
+	}
+	LendingAccountRepayDiscriminator = [...]byte{
+		0x4f, 0xd1, 0xac, 0xb1, 0xde, 0x33, 0xad, 0x97,
+	}
+	LendingAccountStartFlashloanDiscriminator = [...]byte{
+		0x0e, 0x83, 0x21, 0xdc, 0x51, 0xba, 0xb4, 0x6b,
+	}
+	LendingAccountEndFlashloanDiscriminator = [...]byte{
+		0x69, 0x7c, 0xc9, 0x6a, 0x99, 0x02, 0x08, 0x9c,
+	}
+)
+
+var (
+	ErrBankNotFound       = errors.New("bank not found")
+	ErrNoFreeBalanceSlots = errors.New("no free balance slots")
+)
+
+const (
+	LendingAccountLiquidateInstructionSize      = 8 + 8         // bytes
+	LendingAccountWithdrawInstructionSize       = 8 + 8 + 1 + 1 // amount + Option<bool>
+	LendingAccountRepayInstructionSize          = 8 + 8 + 1 + 1 // amount + Option<bool>
+	LendingAccountStartFlashloanInstructionSize = 8 + 8         // bytes
+)
+
+func FindLiquidityVaultAuthority(bankPK solana.PublicKey) (solana.PublicKey, error) {
+	authority, _, err := solana.FindProgramAddress([][]byte{liquidityVaultAuthoritySeed, bankPK.Bytes()}, ProgramAddress)
+	return authority, err
+}
+
+// MakeInitializeMarginfiAccountIx marginfiAccount is a fresh keypair, it has to sign the transaction
+func MakeInitializeMarginfiAccountIx(group solana.PublicKey, marginfiAccount solana.PublicKey, authority solana.PublicKey, feePayer solana.PublicKey) solana.Instruction {
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(group, false, false),
+		solana.NewAccountMeta(marginfiAccount, true, true),
+		solana.NewAccountMeta(authority, false, true),
+		solana.NewAccountMeta(feePayer, true, true),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}
+
+	return solana.NewInstruction(ProgramAddress, accounts, MarginfiAccountInitializeDiscriminator[:])
+}
+
+// CreateLiquidatorAccount creates and initialises a new marginfi account in GroupAddress owned by authority,
+// the transaction is sent without waiting for confirmation
+func CreateLiquidatorAccount(connection *rpc.Client, authority solana.PrivateKey) (solana.PublicKey, solana.Signature, error) {
+	accountKey, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+
+	blockhash, err := connection.GetLatestBlockhash(context.Background(), rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+
+	ix := MakeInitializeMarginfiAccountIx(GroupAddress, accountKey.PublicKey(), authority.PublicKey(), authority.PublicKey())
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, blockhash.Value.Blockhash, solana.TransactionPayer(authority.PublicKey()))
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		switch {
+		case authority.PublicKey().Equals(key):
+			return &authority
+		case accountKey.PublicKey().Equals(key):
+			return &accountKey
+		}
+		return nil
+	})
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+
+	sig, err := connection.SendTransaction(context.Background(), tx)
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+
+	return accountKey.PublicKey(), sig, nil
+}
+
+// ObservationAccounts returns bank and oracle pairs for every active balance the way the risk engine reads them,
+// banks in mandatoryBanks that the account doesn't have yet take the first free balance slot
+func ObservationAccounts(banks BankMap, account *MarginfiAccount, mandatoryBanks ...solana.PublicKey) (solana.AccountMetaSlice, error) {
+	var projected [MaxBalances]*solana.PublicKey
+	for i := range account.LendingAccount.Balances {
+		if account.LendingAccount.Balances[i].Active {
+			projected[i] = &account.LendingAccount.Balances[i].BankPK
+		}
+	}
+
+	for i := range mandatoryBanks {
+		bankPK := &mandatoryBanks[i]
+		present := false
+		freeSlot := -1
+		for slot, projectedBankPK := range projected {
+			if projectedBankPK == nil {
+				if freeSlot == -1 {
+					freeSlot = slot
+				}
+				continue
+			}
+			if *projectedBankPK == *bankPK {
+				present = true
+				break
+			}
+		}
+		if present {
+			continue
+		}
+		if freeSlot == -1 {
+			return nil, ErrNoFreeBalanceSlots
+		}
+		projected[freeSlot] = bankPK
+	}
+
+	accounts := make(solana.AccountMetaSlice, 0, 2*MaxBalances)
+	for _, bankPK := range projected {
+		if bankPK == nil {
+			continue
+		}
+		bank := banks[*bankPK]
+		if bank == nil {
+			return nil, ErrBankNotFound
+		}
+		accounts = append(accounts,
+			solana.NewAccountMeta(*bankPK, false, false),
+			solana.NewAccountMeta(bank.OracleAccount(), false, false),
+		)
+	}
+	return accounts, nil
+}
+
+// MakeLiquidateIx builds lending_account_liquidate, liquidator takes assetAmount (native units) of liquidatee's
+// asset bank deposit and takes over the matching part of its liability bank debt
+func MakeLiquidateIx(
+	banks BankMap,
+	liquidatorAccountPK solana.PublicKey,
+	liquidatorAccount *MarginfiAccount,
+	liquidateeAccountPK solana.PublicKey,
+	liquidateeAccount *MarginfiAccount,
+	assetBankPK solana.PublicKey,
+	liabilityBankPK solana.PublicKey,
+	assetAmount uint64,
+) (solana.Instruction, error) {
+	assetBank := banks[assetBankPK]
+	liabilityBank := banks[liabilityBankPK]
+	if assetBank == nil || liabilityBank == nil {
+		return nil, ErrBankNotFound
+	}
+
+	liquidityVaultAuthority, err := FindLiquidityVaultAuthority(liabilityBankPK)
+	if err != nil {
+		return nil, err
+	}
+
+	liquidatorObservationAccounts, err := ObservationAccounts(banks, liquidatorAccount, assetBankPK, liabilityBankPK)
+	if err != nil {
+		return nil, err
+	}
+	liquidateeObservationAccounts, err := ObservationAccounts(banks, liquidateeAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(solana.AccountMetaSlice, 0, 12+len(liquidatorObservationAccounts)+len(liquidateeObservationAccounts))
+	accounts = append(accounts,
+		solana.NewAccountMeta(liabilityBank.Group, false, false),
+		solana.NewAccountMeta(assetBankPK, true, false),
+		solana.NewAccountMeta(liabilityBankPK, true, false),
+		solana.NewAccountMeta(liquidatorAccountPK, true, false),
+		solana.NewAccountMeta(liquidatorAccount.Authority, false, true),
+		solana.NewAccountMeta(liquidateeAccountPK, true, false),
+		solana.NewAccountMeta(liquidityVaultAuthority, true, false),
+		solana.NewAccountMeta(liabilityBank.LiquidityVault, true, false),
+		solana.NewAccountMeta(liabilityBank.InsuranceVault, true, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+		// remaining accounts
+		solana.NewAccountMeta(assetBank.OracleAccount(), false, false),
+		solana.NewAccountMeta(liabilityBank.OracleAccount(), false, false),
+	)
+	accounts = append(accounts, liquidatorObservationAccounts...)
+	accounts = append(accounts, liquidateeObservationAccounts...)
+
+	data := make([]byte, LendingAccountLiquidateInstructionSize)
+	copy(data, LendingAccountLiquidateDiscriminator[:])
+	bin.LE.PutUint64(data[8:], assetAmount)
+
+	return solana.NewInstruction(ProgramAddress, accounts, data), nil
+}
+
+// MakeWithdrawIx with withdrawAll the balance is closed and amount is ignored,
+// observationAccounts can be empty inside a flashloan since health check is deferred to its end
+func MakeWithdrawIx(
+	marginfiAccountPK solana.PublicKey,
+	marginfiAccount *MarginfiAccount,
+	bankPK solana.PublicKey,
+	bank *Bank,
+	destinationTokenAccount solana.PublicKey,
+	amount uint64,
+	withdrawAll bool,
+	observationAccounts solana.AccountMetaSlice,
+) (solana.Instruction, error) {
+	liquidityVaultAuthority, err := FindLiquidityVaultAuthority(bankPK)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(solana.AccountMetaSlice, 0, 8+len(observationAccounts))
+	accounts = append(accounts,
+		solana.NewAccountMeta(bank.Group, false, false),
+		solana.NewAccountMeta(marginfiAccountPK, true, false),
+		solana.NewAccountMeta(marginfiAccount.Authority, false, true),
+		solana.NewAccountMeta(bankPK, true, false),
+		solana.NewAccountMeta(destinationTokenAccount, true, false),
+		solana.NewAccountMeta(liquidityVaultAuthority, true, false),
+		solana.NewAccountMeta(bank.LiquidityVault, true, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+	)
+	accounts = append(accounts, observationAccounts...)
+
+	data := make([]byte, LendingAccountWithdrawInstructionSize)
+	copy(data, LendingAccountWithdrawDiscriminator[:])
+	bin.LE.PutUint64(data[8:], amount)
+	putOptionBool(data[16:], withdrawAll)
+
+	return solana.NewInstruction(ProgramAddress, accounts, data), nil
+}
+
+// MakeRepayIx with repayAll the balance is closed and amount is ignored
+func MakeRepayIx(
+	marginfiAccountPK solana.PublicKey,
+	marginfiAccount *MarginfiAccount,
+	bankPK solana.PublicKey,
+	bank *Bank,
+	sourceTokenAccount solana.PublicKey,
+	amount uint64,
+	repayAll bool,
+) solana.Instruction {
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(bank.Group, false, false),
+		solana.NewAccountMeta(marginfiAccountPK, true, false),
+		solana.NewAccountMeta(marginfiAccount.Authority, false, true),
+		solana.NewAccountMeta(bankPK, true, false),
+		solana.NewAccountMeta(sourceTokenAccount, true, false),
+		solana.NewAccountMeta(bank.LiquidityVault, true, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+	}
+
+	data := make([]byte, LendingAccountRepayInstructionSize)
+	copy(data, LendingAccountRepayDiscriminator[:])
+	bin.LE.PutUint64(data[8:], amount)
+	putOptionBool(data[16:], repayAll)
+
+	return solana.NewInstruction(ProgramAddress, accounts, data)
+}
+
+// MakeStartFlashloanIx endIndex is position of the end flashloan instruction in the transaction
+func MakeStartFlashloanIx(marginfiAccountPK solana.PublicKey, marginfiAccount *MarginfiAccount, endIndex uint64) solana.Instruction {
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(marginfiAccountPK, true, false),
+		solana.NewAccountMeta(marginfiAccount.Authority, false, true),
+		solana.NewAccountMeta(solana.SysVarInstructionsPubkey, false, false),
+	}
+
+	data := make([]byte, LendingAccountStartFlashloanInstructionSize)
+	copy(data, LendingAccountStartFlashloanDiscriminator[:])
+	bin.LE.PutUint64(data[8:], endIndex)
+
+	return solana.NewInstruction(ProgramAddress, accounts, data)
+}
+
+// MakeEndFlashloanIx observationAccounts have to describe the account state after the flashloan
+func MakeEndFlashloanIx(marginfiAccountPK solana.PublicKey, marginfiAccount *MarginfiAccount, observationAccounts solana.AccountMetaSlice) solana.Instruction {
+	accounts := make(solana.AccountMetaSlice, 0, 2+len(observationAccounts))
+	accounts = append(accounts,
+		solana.NewAccountMeta(marginfiAccountPK, true, false),
+		solana.NewAccountMeta(marginfiAccount.Authority, false, true),
+	)
+	accounts = append(accounts, observationAccounts...)
+
+	return solana.NewInstruction(ProgramAddress, accounts, LendingAccountEndFlashloanDiscriminator[:])
+}
+
+func putOptionBool(data []byte, value bool) {
+	data[0] = 1 // Some
+	if value {
+		data[1] = 1
+	}
+}
@@ -0,0 +1,214 @@
+package marginfi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestObservationAccounts(t *testing.T) {
+	bankA, bankB, bankC := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	oracleA, oracleB, oracleC := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	banks := BankMap{
+		bankA: &Bank{Config: BankConfig{OracleKeys: [5]solana.PublicKey{oracleA}}},
+		bankB: &Bank{Config: BankConfig{OracleKeys: [5]solana.PublicKey{oracleB}}},
+		bankC: &Bank{Config: BankConfig{OracleKeys: [5]solana.PublicKey{oracleC}}},
+	}
+
+	account := &MarginfiAccount{}
+	// slot 0 is free, new bank must take it and go before bankA
+	account.LendingAccount.Balances[1] = Balance{Active: true, BankPK: bankA}
+	account.LendingAccount.Balances[3] = Balance{Active: true, BankPK: bankB}
+
+	accounts, err := ObservationAccounts(banks, account, bankB, bankC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []solana.PublicKey{bankC, oracleC, bankA, oracleA, bankB, oracleB}
+	if len(accounts) != len(expected) {
+		t.Fatalf("got %d accounts, expected %d", len(accounts), len(expected))
+	}
+	for i, meta := range accounts {
+		if meta.PublicKey != expected[i] || meta.IsWritable || meta.IsSigner {
+			t.Errorf("account %d: got %v, expected %v", i, meta.PublicKey, expected[i])
+		}
+	}
+}
+
+func TestInstructionLayouts(t *testing.T) {
+	key := func() solana.PublicKey { return solana.NewWallet().PublicKey() }
+	group, authority, tokenAccount := key(), key(), key()
+	liquidatorPK, liquidateePK := key(), key()
+	assetBankPK, liabilityBankPK := key(), key()
+	assetOracle, liabilityOracle := key(), key()
+	assetBank := &Bank{Group: group, LiquidityVault: key(), InsuranceVault: key(), Config: BankConfig{OracleKeys: [5]solana.PublicKey{assetOracle}}}
+	liabilityBank := &Bank{Group: group, LiquidityVault: key(), InsuranceVault: key(), Config: BankConfig{OracleKeys: [5]solana.PublicKey{liabilityOracle}}}
+	banks := BankMap{assetBankPK: assetBank, liabilityBankPK: liabilityBank}
+
+	liquidator := &MarginfiAccount{Authority: authority}
+	liquidatee := &MarginfiAccount{}
+	liquidatee.LendingAccount.Balances[0] = Balance{Active: true, BankPK: liabilityBankPK}
+	liquidatee.LendingAccount.Balances[2] = Balance{Active: true, BankPK: assetBankPK}
+
+	vaultAuthority := func(bankPK solana.PublicKey) solana.PublicKey {
+		address, _, err := solana.FindProgramAddress([][]byte{[]byte("liquidity_vault_auth"), bankPK.Bytes()}, ProgramAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+	observation := solana.AccountMetaSlice{solana.NewAccountMeta(assetBankPK, false, false), solana.NewAccountMeta(assetOracle, false, false)}
+
+	mustIx := func(ix solana.Instruction, err error) solana.Instruction {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ix
+	}
+
+	tests := []struct {
+		name     string
+		ix       solana.Instruction
+		accounts solana.AccountMetaSlice
+		data     []byte
+	}{
+		{
+			name: "liquidate",
+			ix:   mustIx(MakeLiquidateIx(banks, liquidatorPK, liquidator, liquidateePK, liquidatee, assetBankPK, liabilityBankPK, 1_000_000)),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(group, false, false),
+				solana.NewAccountMeta(assetBankPK, true, false),
+				solana.NewAccountMeta(liabilityBankPK, true, false),
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(liquidateePK, true, false),
+				solana.NewAccountMeta(vaultAuthority(liabilityBankPK), true, false),
+				solana.NewAccountMeta(liabilityBank.LiquidityVault, true, false),
+				solana.NewAccountMeta(liabilityBank.InsuranceVault, true, false),
+				solana.NewAccountMeta(solana.TokenProgramID, false, false),
+				solana.NewAccountMeta(assetOracle, false, false),
+				solana.NewAccountMeta(liabilityOracle, false, false),
+				// liquidator after the liquidation, banks take free slots in order
+				solana.NewAccountMeta(assetBankPK, false, false),
+				solana.NewAccountMeta(assetOracle, false, false),
+				solana.NewAccountMeta(liabilityBankPK, false, false),
+				solana.NewAccountMeta(liabilityOracle, false, false),
+				// liquidatee in balance order
+				solana.NewAccountMeta(liabilityBankPK, false, false),
+				solana.NewAccountMeta(liabilityOracle, false, false),
+				solana.NewAccountMeta(assetBankPK, false, false),
+				solana.NewAccountMeta(assetOracle, false, false),
+			},
+			data: []byte{0xd6, 0xa9, 0x97, 0xd5, 0xfb, 0xa7, 0x56, 0xdb, 0x40, 0x42, 0x0f, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "withdraw",
+			ix:   mustIx(MakeWithdrawIx(liquidatorPK, liquidator, assetBankPK, assetBank, tokenAccount, 1_000_000, false, observation)),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(group, false, false),
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(assetBankPK, true, false),
+				solana.NewAccountMeta(tokenAccount, true, false),
+				solana.NewAccountMeta(vaultAuthority(assetBankPK), true, false),
+				solana.NewAccountMeta(assetBank.LiquidityVault, true, false),
+				solana.NewAccountMeta(solana.TokenProgramID, false, false),
+				solana.NewAccountMeta(assetBankPK, false, false),
+				solana.NewAccountMeta(assetOracle, false, false),
+			},
+			data: []byte{0x24, 0x48, 0x4a, 0x13, 0xd2, 0xd2, 0xc0, 0xc0, 0x40, 0x42, 0x0f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00},
+		},
+		{
+			name: "withdraw all",
+			ix:   mustIx(MakeWithdrawIx(liquidatorPK, liquidator, assetBankPK, assetBank, tokenAccount, 0, true, nil)),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(group, false, false),
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(assetBankPK, true, false),
+				solana.NewAccountMeta(tokenAccount, true, false),
+				solana.NewAccountMeta(vaultAuthority(assetBankPK), true, false),
+				solana.NewAccountMeta(assetBank.LiquidityVault, true, false),
+				solana.NewAccountMeta(solana.TokenProgramID, false, false),
+			},
+			data: []byte{0x24, 0x48, 0x4a, 0x13, 0xd2, 0xd2, 0xc0, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01},
+		},
+		{
+			name: "repay",
+			ix:   MakeRepayIx(liquidatorPK, liquidator, liabilityBankPK, liabilityBank, tokenAccount, 500, false),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(group, false, false),
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(liabilityBankPK, true, false),
+				solana.NewAccountMeta(tokenAccount, true, false),
+				solana.NewAccountMeta(liabilityBank.LiquidityVault, true, false),
+				solana.NewAccountMeta(solana.TokenProgramID, false, false),
+			},
+			data: []byte{0x4f, 0xd1, 0xac, 0xb1, 0xde, 0x33, 0xad, 0x97, 0xf4, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00},
+		},
+		{
+			name: "repay all",
+			ix:   MakeRepayIx(liquidatorPK, liquidator, liabilityBankPK, liabilityBank, tokenAccount, 0, true),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(group, false, false),
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(liabilityBankPK, true, false),
+				solana.NewAccountMeta(tokenAccount, true, false),
+				solana.NewAccountMeta(liabilityBank.LiquidityVault, true, false),
+				solana.NewAccountMeta(solana.TokenProgramID, false, false),
+			},
+			data: []byte{0x4f, 0xd1, 0xac, 0xb1, 0xde, 0x33, 0xad, 0x97, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01},
+		},
+		{
+			name: "start flashloan",
+			ix:   MakeStartFlashloanIx(liquidatorPK, liquidator, 7),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(solana.SysVarInstructionsPubkey, false, false),
+			},
+			data: []byte{0x0e, 0x83, 0x21, 0xdc, 0x51, 0xba, 0xb4, 0x6b, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "end flashloan",
+			ix:   MakeEndFlashloanIx(liquidatorPK, liquidator, observation),
+			accounts: solana.AccountMetaSlice{
+				solana.NewAccountMeta(liquidatorPK, true, false),
+				solana.NewAccountMeta(authority, false, true),
+				solana.NewAccountMeta(assetBankPK, false, false),
+				solana.NewAccountMeta(assetOracle, false, false),
+			},
+			data: []byte{0x69, 0x7c, 0xc9, 0x6a, 0x99, 0x02, 0x08, 0x9c},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ix.ProgramID() != ProgramAddress {
+				t.Errorf("program %v, expected %v", tt.ix.ProgramID(), ProgramAddress)
+			}
+			accounts := tt.ix.Accounts()
+			if len(accounts) != len(tt.accounts) {
+				t.Fatalf("got %d accounts, expected %d", len(accounts), len(tt.accounts))
+			}
+			for i, meta := range accounts {
+				expected := tt.accounts[i]
+				if meta.PublicKey != expected.PublicKey || meta.IsWritable != expected.IsWritable || meta.IsSigner != expected.IsSigner {
+					t.Errorf("account %d: got %v w=%v s=%v, expected %v w=%v s=%v", i,
+						meta.PublicKey, meta.IsWritable, meta.IsSigner, expected.PublicKey, expected.IsWritable, expected.IsSigner)
+				}
+			}
+			data, err := tt.ix.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.data) {
+				t.Errorf("data %x, expected %x", data, tt.data)
+			}
+		})
+	}
+}
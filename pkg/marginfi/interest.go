@@ -0,0 +1,139 @@
+package marginfi
+
+import (
+	"errors"
+	"jito-bot/pkg/fixed"
+)
+
+const SecondsPerYear = 31_536_000
+
+var ErrInterestAccrual = errors.New("interest accrual failed")
+
+// InterestRateConfig size 16 * 7 + 128 (padding) = 240
+type InterestRateConfig struct {
+	OptimalUtilizationRate fixed.I80F48
+	PlateauInterestRate    fixed.I80F48
+	MaxInterestRate        fixed.I80F48
+
+	InsuranceFeeFixedApr fixed.I80F48
+	InsuranceIrFee       fixed.I80F48
+	ProtocolFixedFeeApr  fixed.I80F48
+	ProtocolIrFee        fixed.I80F48
+	// padding u128x8
+}
+
+func ParseInterestRateConfig(data []byte) InterestRateConfig {
+	return InterestRateConfig{
+		OptimalUtilizationRate: fixed.MustI80F48FromLittleEndian(data[0:16]),
+		PlateauInterestRate:    fixed.MustI80F48FromLittleEndian(data[16:32]),
+		MaxInterestRate:        fixed.MustI80F48FromLittleEndian(data[32:48]),
+
+		InsuranceFeeFixedApr: fixed.MustI80F48FromLittleEndian(data[48:64]),
+		InsuranceIrFee:       fixed.MustI80F48FromLittleEndian(data[64:80]),
+		ProtocolFixedFeeApr:  fixed.MustI80F48FromLittleEndian(data[80:96]),
+		ProtocolIrFee:        fixed.MustI80F48FromLittleEndian(data[96:112]),
+	}
+}
+
+// InterestRateCurve is the base rate for the utilization ratio, linear up to the optimal (plateau) rate
+// and then linear again up to the max rate at 100% utilization
+func (c *InterestRateConfig) InterestRateCurve(utilizationRatio fixed.I80F48) (fixed.I80F48, bool) {
+	if utilizationRatio.LessThanOrEqual(c.OptimalUtilizationRate) {
+		if c.OptimalUtilizationRate.IsZero() {
+			return fixed.I80F48{}, false
+		}
+		return utilizationRatio.Div(c.OptimalUtilizationRate).Mul(c.PlateauInterestRate), true
+	}
+
+	if !c.OptimalUtilizationRate.LessThan(fixed.I80f48One) || c.MaxInterestRate.LessThan(c.PlateauInterestRate) {
+		return fixed.I80F48{}, false
+	}
+	return utilizationRatio.Sub(c.OptimalUtilizationRate).
+		Div(fixed.I80f48One.Sub(c.OptimalUtilizationRate)).
+		Mul(c.MaxInterestRate.Sub(c.PlateauInterestRate)).
+		Add(c.PlateauInterestRate), true
+}
+
+// CalcInterestRate returns lending, borrowing, group fee and insurance fee APRs
+func (c *InterestRateConfig) CalcInterestRate(utilizationRatio fixed.I80F48) (lendingApr, borrowingApr, groupFeeApr, insuranceFeeApr fixed.I80F48, ok bool) {
+	rateFee := c.ProtocolIrFee.Add(c.InsuranceIrFee)
+	totalFixedFeeApr := c.ProtocolFixedFeeApr.Add(c.InsuranceFeeFixedApr)
+
+	baseRate, ok := c.InterestRateCurve(utilizationRatio)
+	if !ok {
+		return
+	}
+
+	// lending rate is adjusted for utilization ratio to symmetrize payments between borrowers and depositors
+	lendingApr = baseRate.Mul(utilizationRatio)
+	// borrowing rate = base_rate + base_rate * rate_fee + total_fixed_fee_apr
+	borrowingApr = baseRate.Mul(fixed.I80f48One.Add(rateFee)).Add(totalFixedFeeApr)
+	groupFeeApr = baseRate.Mul(c.ProtocolIrFee).Add(c.ProtocolFixedFeeApr)
+	insuranceFeeApr = baseRate.Mul(c.InsuranceIrFee).Add(c.InsuranceFeeFixedApr)
+	return
+}
+
+// AccrueInterest is a port of marginfi Bank::accrue_interest, it moves share values and collected fees to currentTimestamp
+func (b *Bank) AccrueInterest(currentTimestamp int64) error {
+	if currentTimestamp < b.LastUpdate {
+		return ErrInterestAccrual
+	}
+	timeDelta := uint64(currentTimestamp - b.LastUpdate)
+	if timeDelta == 0 {
+		return nil
+	}
+
+	totalAssets := b.GetAssetQuantity(b.TotalAssetShares)
+	totalLiabilities := b.GetLiabilityQuantity(b.TotalLiabilityShares)
+
+	b.LastUpdate = currentTimestamp
+
+	if totalAssets.IsZero() || totalLiabilities.IsZero() {
+		return nil
+	}
+
+	utilizationRatio := totalLiabilities.Div(totalAssets)
+	lendingApr, borrowingApr, groupFeeApr, insuranceFeeApr, ok := b.Config.InterestRateConfig.CalcInterestRate(utilizationRatio)
+	if !ok {
+		return ErrInterestAccrual
+	}
+
+	b.AssetShareValue = calcAccruedInterestPaymentPerPeriod(lendingApr, timeDelta, b.AssetShareValue)
+	b.LiabilityShareValue = calcAccruedInterestPaymentPerPeriod(borrowingApr, timeDelta, b.LiabilityShareValue)
+	b.CollectedGroupFeesOutstanding = b.CollectedGroupFeesOutstanding.Add(calcInterestPaymentForPeriod(groupFeeApr, timeDelta, totalLiabilities))
+	b.CollectedInsuranceFeesOutstanding = b.CollectedInsuranceFeesOutstanding.Add(calcInterestPaymentForPeriod(insuranceFeeApr, timeDelta, totalLiabilities))
+
+	return nil
+}
+
+// WithAccruedInterest returns a copy of the bank projected to currentTimestamp, the bank itself is not modified
+func (b *Bank) WithAccruedInterest(currentTimestamp int64) (*Bank, error) {
+	projected := *b
+	if err := projected.AccrueInterest(currentTimestamp); err != nil {
+		return nil, err
+	}
+	return &projected, nil
+}
+
+// WithAccruedInterest projects every bank to currentTimestamp, banks that fail to accrue are kept as they are
+func (m BankMap) WithAccruedInterest(currentTimestamp int64) BankMap {
+	projected := make(BankMap, len(m))
+	for bankPK, bank := range m {
+		projectedBank, err := bank.WithAccruedInterest(currentTimestamp)
+		if err != nil {
+			projected[bankPK] = bank
+			continue
+		}
+		projected[bankPK] = projectedBank
+	}
+	return projected
+}
+
+func calcAccruedInterestPaymentPerPeriod(apr fixed.I80F48, timeDelta uint64, value fixed.I80F48) fixed.I80F48 {
+	irPerPeriod := apr.Mul64(timeDelta).Div64(SecondsPerYear)
+	return value.Mul(fixed.I80f48One.Add(irPerPeriod))
+}
+
+func calcInterestPaymentForPeriod(apr fixed.I80F48, timeDelta uint64, value fixed.I80F48) fixed.I80F48 {
+	return value.Mul(apr).Mul64(timeDelta).Div64(SecondsPerYear)
+}
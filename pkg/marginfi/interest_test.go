@@ -0,0 +1,65 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"math"
+	"testing"
+)
+
+func testInterestRateConfig() InterestRateConfig {
+	return InterestRateConfig{
+		OptimalUtilizationRate: fixed.MustI80F48FromFloat64(0.8),
+		PlateauInterestRate:    fixed.MustI80F48FromFloat64(0.1),
+		MaxInterestRate:        fixed.MustI80F48FromFloat64(1),
+	}
+}
+
+func TestInterestRateCurve(t *testing.T) {
+	config := testInterestRateConfig()
+	cases := []struct {
+		utilization float64
+		expected    float64
+	}{
+		{0, 0},
+		{0.4, 0.05},
+		{0.8, 0.1},
+		{0.9, 0.55},
+		{1, 1},
+	}
+	for _, c := range cases {
+		rate, ok := config.InterestRateCurve(fixed.MustI80F48FromFloat64(c.utilization))
+		if !ok {
+			t.Fatalf("utilization %v: curve failed", c.utilization)
+		}
+		if math.Abs(rate.AsFloat64()-c.expected) > 1e-9 {
+			t.Errorf("utilization %v: got %v, expected %v", c.utilization, rate.AsFloat64(), c.expected)
+		}
+	}
+}
+
+func TestAccrueInterest(t *testing.T) {
+	bank := &Bank{
+		AssetShareValue:      fixed.I80f48One,
+		LiabilityShareValue:  fixed.I80f48One,
+		TotalAssetShares:     fixed.MustI80F48FromFloat64(100),
+		TotalLiabilityShares: fixed.MustI80F48FromFloat64(50),
+		Config: BankConfig{
+			InterestRateConfig: testInterestRateConfig(),
+		},
+	}
+
+	projected, err := bank.WithAccruedInterest(SecondsPerYear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bank.LastUpdate != 0 || projected.LastUpdate != SecondsPerYear {
+		t.Fatal("projection must not modify the original bank")
+	}
+	// utilization 0.5 -> base rate 0.0625, lending rate 0.03125
+	if math.Abs(projected.AssetShareValue.AsFloat64()-1.03125) > 1e-9 {
+		t.Errorf("asset share value %v", projected.AssetShareValue.AsFloat64())
+	}
+	if math.Abs(projected.LiabilityShareValue.AsFloat64()-1.0625) > 1e-9 {
+		t.Errorf("liability share value %v", projected.LiabilityShareValue.AsFloat64())
+	}
+}
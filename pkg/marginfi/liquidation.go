@@ -0,0 +1,136 @@
+package marginfi
+
+import (
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	LiquidationLiquidatorFee = 0.025 // 2.5%
+	LiquidationInsuranceFee  = 0.025 // 2.5%
+
+	// DefaultLiquidationSafetyMargin keeps liquidatee slightly below maintenance so rounding and
+	// price moves between simulation and execution don't make the program reject the liquidation
+	DefaultLiquidationSafetyMargin = 0.01
+)
+
+type LiquidationSolverOptions struct {
+	SafetyMargin float64
+	// MaxAssetUsdValue caps seized collateral value, zero means unlimited (flashloan funded)
+	MaxAssetUsdValue float64
+}
+
+type LiquidationPlan struct {
+	AssetBankPK     solana.PublicKey
+	LiabilityBankPK solana.PublicKey
+	// AssetAmount native units of asset bank token liquidator receives, this is lending_account_liquidate argument
+	AssetAmount uint64
+	// LiabilityAmount native units of liability bank token liquidator takes over
+	LiabilityAmount uint64
+	// ProfitUsd is liquidator fee on the seized collateral
+	ProfitUsd float64
+	// HealthBefore and HealthAfter are liquidatee maintenance health, assets - liabilities in USD
+	HealthBefore float64
+	HealthAfter  float64
+}
+
+// FindBestLiquidation picks the (asset bank, liability bank, asset amount) triple that maximises liquidator profit,
+// liquidatee must stay at or below maintenance after liquidation otherwise the program rejects it
+func (m *MarginfiAccount) FindBestLiquidation(banks BankMap, oraclePrices OraclePriceMap, opts LiquidationSolverOptions) (*LiquidationPlan, bool) {
+	assets, liabilities := m.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeMaintenance)
+	healthBefore := assets.AsFloat64() - liabilities.AsFloat64()
+	if healthBefore >= 0 {
+		return nil, false
+	}
+
+	var best *LiquidationPlan
+	for _, assetBalance := range m.LendingAccount.Balances {
+		if !assetBalance.Active || assetBalance.AssetShares.IsZero() {
+			continue
+		}
+		for _, liabilityBalance := range m.LendingAccount.Balances {
+			if !liabilityBalance.Active || liabilityBalance.LiabilityShares.IsZero() || liabilityBalance.BankPK == assetBalance.BankPK {
+				continue
+			}
+			plan, ok := solveLiquidation(banks, oraclePrices, &assetBalance, &liabilityBalance, healthBefore, opts)
+			if !ok {
+				continue
+			}
+			if best == nil || plan.ProfitUsd > best.ProfitUsd {
+				best = plan
+			}
+		}
+	}
+
+	return best, best != nil
+}
+
+func solveLiquidation(
+	banks BankMap, oraclePrices OraclePriceMap, assetBalance *Balance, liabilityBalance *Balance, healthBefore float64, opts LiquidationSolverOptions,
+) (*LiquidationPlan, bool) {
+	assetBank, liabilityBank := banks[assetBalance.BankPK], banks[liabilityBalance.BankPK]
+	assetPrice, liabilityPrice := oraclePrices[assetBalance.BankPK], oraclePrices[liabilityBalance.BankPK]
+	if assetBank == nil || liabilityBank == nil || assetPrice == nil || liabilityPrice == nil {
+		return nil, false
+	}
+	// liquidator deposits asset and borrows liability, both banks have to accept it
+	if assetBank.Config.OperationalState != BankOperationalStateOperational || liabilityBank.Config.OperationalState != BankOperationalStateOperational {
+		return nil, false
+	}
+
+	assetScale := math.Pow10(int(assetBank.MintDecimals))
+	liabilityScale := math.Pow10(int(liabilityBank.MintDecimals))
+
+	// liquidation itself is priced with realtime price without bias
+	assetPriceNone := GetPrice(assetPrice, PriceBiasNone, false).AsFloat64()
+	liabilityPriceNone := GetPrice(liabilityPrice, PriceBiasNone, false).AsFloat64()
+	if assetPriceNone <= 0 || liabilityPriceNone <= 0 {
+		return nil, false
+	}
+	// health is priced with biased prices and maintenance weights
+	assetPriceLowest := GetPrice(assetPrice, PriceBiasLowest, false).AsFloat64()
+	liabilityPriceHighest := GetPrice(liabilityPrice, PriceBiasHighest, false).AsFloat64()
+	assetWeight := assetBank.GetAssetWeight(MarginRequirementTypeMaintenance, assetPrice).AsFloat64()
+	liabilityWeight := liabilityBank.GetLiabilityWeight(MarginRequirementTypeMaintenance).AsFloat64()
+
+	finalDiscount := 1 - (LiquidationLiquidatorFee + LiquidationInsuranceFee)
+	liquidatorDiscount := 1 - LiquidationLiquidatorFee
+
+	// per one native unit of seized asset
+	assetValue := assetPriceNone / assetScale
+	liabilityRepaid := assetValue * finalDiscount / liabilityPriceNone * liabilityScale
+	assetHealthDecrease := assetPriceLowest * assetWeight / assetScale
+	liabilityHealthIncrease := liabilityRepaid / liabilityScale * liabilityPriceHighest * liabilityWeight
+
+	healthGain := liabilityHealthIncrease - assetHealthDecrease
+	if healthGain <= 0 {
+		// program requires liquidation to improve health
+		return nil, false
+	}
+
+	assetAmount := -healthBefore / healthGain * (1 - opts.SafetyMargin)
+	if maxAsset := assetBank.GetAssetQuantity(assetBalance.AssetShares).AsFloat64(); assetAmount > maxAsset {
+		assetAmount = maxAsset
+	}
+	if maxByLiability := liabilityBank.GetLiabilityQuantity(liabilityBalance.LiabilityShares).AsFloat64() / liabilityRepaid; assetAmount > maxByLiability {
+		assetAmount = maxByLiability
+	}
+	if opts.MaxAssetUsdValue > 0 && assetAmount*assetValue > opts.MaxAssetUsdValue {
+		assetAmount = opts.MaxAssetUsdValue / assetValue
+	}
+	assetAmount = math.Floor(assetAmount)
+	if assetAmount < 1 {
+		return nil, false
+	}
+
+	return &LiquidationPlan{
+		AssetBankPK:     assetBalance.BankPK,
+		LiabilityBankPK: liabilityBalance.BankPK,
+		AssetAmount:     uint64(assetAmount),
+		LiabilityAmount: uint64(math.Ceil(assetAmount * assetValue * liquidatorDiscount / liabilityPriceNone * liabilityScale)),
+		ProfitUsd:       assetAmount * assetValue * LiquidationLiquidatorFee,
+		HealthBefore:    healthBefore,
+		HealthAfter:     healthBefore + assetAmount*healthGain,
+	}, true
+}
@@ -0,0 +1,72 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestFindBestLiquidation(t *testing.T) {
+	solBankPK, usdcBankPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	banks := BankMap{
+		solBankPK: &Bank{
+			MintDecimals:        9,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(0.9),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+				OperationalState:     BankOperationalStateOperational,
+			},
+		},
+		usdcBankPK: &Bank{
+			MintDecimals:        6,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(1),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+				OperationalState:     BankOperationalStateOperational,
+			},
+		},
+	}
+	oraclePrices := OraclePriceMap{
+		solBankPK:  &OraclePrice{PriceRealtime: NewPriceWithConfidence(100, 0)},
+		usdcBankPK: &OraclePrice{PriceRealtime: NewPriceWithConfidence(1, 0)},
+	}
+
+	account := &MarginfiAccount{}
+	// 1 SOL deposited (90$ weighted), 85 USDC borrowed (93.5$ weighted)
+	account.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e9)}
+	account.LendingAccount.Balances[1] = Balance{Active: true, BankPK: usdcBankPK, LiabilityShares: fixed.MustI80F48FromFloat64(85e6)}
+
+	plan, ok := account.FindBestLiquidation(banks, oraclePrices, LiquidationSolverOptions{SafetyMargin: DefaultLiquidationSafetyMargin})
+	if !ok {
+		t.Fatal("account must be liquidatable")
+	}
+	if plan.AssetBankPK != solBankPK || plan.LiabilityBankPK != usdcBankPK {
+		t.Fatal("wrong banks picked")
+	}
+	if math.Abs(plan.HealthBefore+3.5) > 1e-6 {
+		t.Errorf("health before %v", plan.HealthBefore)
+	}
+	if plan.HealthAfter > 0 || plan.HealthAfter < plan.HealthBefore*DefaultLiquidationSafetyMargin*1.01 {
+		t.Errorf("health after %v", plan.HealthAfter)
+	}
+	// ~0.239 SOL seized, liquidator takes 0.975 of its value as USDC debt
+	if plan.AssetAmount < 238_000_000 || plan.AssetAmount > 240_000_000 {
+		t.Errorf("asset amount %v", plan.AssetAmount)
+	}
+	expectedLiability := float64(plan.AssetAmount) / 1e9 * 100 * 0.975 * 1e6
+	if math.Abs(float64(plan.LiabilityAmount)-expectedLiability) > 1 {
+		t.Errorf("liability amount %v, expected %v", plan.LiabilityAmount, expectedLiability)
+	}
+
+	// healthy account must not be liquidated
+	account.LendingAccount.Balances[1].LiabilityShares = fixed.MustI80F48FromFloat64(50e6)
+	if _, ok := account.FindBestLiquidation(banks, oraclePrices, LiquidationSolverOptions{}); ok {
+		t.Error("healthy account must not be liquidatable")
+	}
+}
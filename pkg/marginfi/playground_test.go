@@ -32,8 +32,9 @@ func TestLiq(t *testing.T) {
 	spew.Dump(balanceSol)
 	//spew.Dump(balanceUSDC)
 
-	bankSol := mfiClient.Banks[balanceSol.BankPK]
-	//bankUSDC := mfiClient.Banks[balanceUSDC.BankPK]
+	banks, _ := mfiClient.Snapshot()
+	bankSol := banks[balanceSol.BankPK]
+	//bankUSDC := banks[balanceUSDC.BankPK]
 
 	b1 := bankSol.GetAssetQuantity(balanceSol.AssetShares).AsFloat64() * math.Pow10(-int(bankSol.MintDecimals))
 	//b2 := bankUSDC.GetAssetQuantity(balanceUSDC.AssetShares).AsFloat64() * math.Pow10(-int(bankSol.MintDecimals))
@@ -46,3 +47,14 @@ func TestLiq(t *testing.T) {
 	maxLiabilityPaydown := assets.Sub(liabilities)
 	spew.Dump(liquidated, assets.AsFloat64(), liabilities.AsFloat64(), maxLiabilityPaydown.AsFloat64())
 }
+
+func TestMarginfiAccountSize(t *testing.T) {
+	acc, err := connection.GetAccountInfo(context.Background(), targetPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := acc.Value.Data.GetBinary()
+	if len(data) != MarginfiAccountSize || !isMarginfiAccount(data) {
+		t.Fatalf("account dump is %d bytes, expected %d", len(data), MarginfiAccountSize)
+	}
+}
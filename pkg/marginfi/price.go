@@ -1,8 +1,12 @@
 package marginfi
 
 import (
+	"errors"
 	"jito-bot/pkg/fixed"
 	"jito-bot/pkg/pyth"
+	"jito-bot/pkg/switchboard"
+
+	"github.com/gagliardetto/solana-go"
 )
 
 type PriceBias uint
@@ -22,32 +26,139 @@ type PriceWithConfidence struct {
 
 type OraclePrice struct {
 	PriceRealtime PriceWithConfidence
-	//PriceWeighted PriceWithConfidence
+	PriceWeighted PriceWithConfidence
+	// Timestamp unix seconds of the price, used for staleness checks
+	Timestamp int64
 }
 
+var (
+	ErrUnsupportedOracleSetup = errors.New("unsupported oracle setup")
+	ErrStaleOraclePrice       = errors.New("oracle price is older than bank's max age")
+)
+
+// MaxPriceAgeSec is used when bank has no oracle max age configured
+const MaxPriceAgeSec = 60
+
+// SwitchboardStdDevMultiple confidence interval for switchboard is std deviation times this multiple
+const SwitchboardStdDevMultiple float64 = 1.96
+
 var PythPriceConfIntervals = fixed.MustI80F48FromFloat64(pyth.PriceConfIntervals)
+var SwitchboardConfIntervals = fixed.MustI80F48FromFloat64(SwitchboardStdDevMultiple)
 
-func ParseOraclePrice(setup OracleSetup, data []byte) *OraclePrice {
-	if setup != OracleSetupPyth {
-		panic("unsupported oracle setup")
+func NewPriceWithConfidence(price float64, conf float64) PriceWithConfidence {
+	return newPriceWithConfidenceIntervals(price, conf, PythPriceConfIntervals)
+}
+
+func newPriceWithConfidenceIntervals(price float64, conf float64, confIntervals fixed.I80F48) PriceWithConfidence {
+	fixedPrice := fixed.MustI80F48FromFloat64(price)
+	fixedConf := fixed.MustI80F48FromFloat64(conf)
+	adjConf := fixedConf.Mul(confIntervals)
+
+	return PriceWithConfidence{
+		Price:        fixedPrice,
+		Conf:         fixedConf,
+		LowestPrice:  fixedPrice.Sub(adjConf),
+		HighestPrice: fixedPrice.Add(adjConf),
 	}
+}
 
-	pythPriceData := pyth.ParsePriceData(data)
+// OracleValidation is applied to pyth prices, invalid prices are refused so health is not computed on them
+var OracleValidation = pyth.DefaultValidationOptions
 
-	priceRealtime := fixed.MustI80F48FromFloat64(pythPriceData.Agg.Price)
-	confRealtime := fixed.MustI80F48FromFloat64(pythPriceData.Agg.Conf)
-	adjConfRealtime := confRealtime.Mul(PythPriceConfIntervals)
+// ParseOraclePrice decodes the oracle account observed at currentSlot
+func ParseOraclePrice(setup OracleSetup, data []byte, currentSlot uint64) (*OraclePrice, error) {
+	switch setup {
+	case OracleSetupPyth:
+		pythPriceData, err := pyth.ParsePriceData(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := pythPriceData.Validate(currentSlot, OracleValidation); err != nil {
+			return nil, err
+		}
+		return &OraclePrice{
+			PriceRealtime: NewPriceWithConfidence(pythPriceData.Agg.Price, pythPriceData.Agg.Conf),
+			PriceWeighted: NewPriceWithConfidence(pythPriceData.EmaPrice.Value, pythPriceData.EmaConf.Value),
+			Timestamp:     pythPriceData.Timestamp,
+		}, nil
+	case OracleSetupSwitchboardV2:
+		aggregator, err := switchboard.ParseAggregatorAccountData(data)
+		if err != nil {
+			return nil, err
+		}
+		result, err := aggregator.GetResult()
+		if err != nil {
+			return nil, err
+		}
+		// switchboard has no time weighted price, both are the latest confirmed round
+		price := newPriceWithConfidenceIntervals(result, aggregator.LatestConfirmedRound.StdDeviation.Float64(), SwitchboardConfIntervals)
+		return &OraclePrice{
+			PriceRealtime: price,
+			PriceWeighted: price,
+			Timestamp:     aggregator.LatestConfirmedRound.RoundOpenTimestamp,
+		}, nil
+	case OracleSetupPythPushOracle:
+		priceUpdate, err := pyth.ParsePriceUpdateV2(data)
+		if err != nil {
+			return nil, err
+		}
+		// program only accepts fully verified updates
+		if !priceUpdate.VerificationLevel.Full {
+			return nil, pyth.ErrPartiallyVerified
+		}
+		message := &priceUpdate.PriceMessage
+		if err := pyth.ValidateConfidence(message.PriceFloat64(), message.ConfFloat64(), OracleValidation.MaxConfRatio); err != nil {
+			return nil, err
+		}
+		return &OraclePrice{
+			PriceRealtime: NewPriceWithConfidence(message.PriceFloat64(), message.ConfFloat64()),
+			PriceWeighted: NewPriceWithConfidence(message.EmaPriceFloat64(), message.EmaConfFloat64()),
+			Timestamp:     message.PublishTime,
+		}, nil
+	default:
+		return nil, ErrUnsupportedOracleSetup
+	}
+}
 
-	//price := pythPriceData.
-	return &OraclePrice{
-		PriceRealtime: PriceWithConfidence{
-			Price:        priceRealtime,
-			Conf:         confRealtime,
-			LowestPrice:  priceRealtime.Sub(adjConfRealtime),
-			HighestPrice: priceRealtime.Add(adjConfRealtime),
-		},
+// ParseBankOraclePrice is ParseOraclePrice of the bank's oracle, prices older than the bank's oracle max age
+// at currentTimestamp are refused as the program refuses them
+func ParseBankOraclePrice(bank *Bank, data []byte, currentSlot uint64, currentTimestamp int64) (*OraclePrice, error) {
+	oraclePrice, err := ParseOraclePrice(bank.Config.OracleSetup, data, currentSlot)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	if oraclePrice.IsStale(bank, currentTimestamp) {
+		return nil, ErrStaleOraclePrice
+	}
+	return oraclePrice, nil
+}
+
+// IsStale tells if the price is older than bank's oracle max age at currentTimestamp, program rejects stale prices
+func (p *OraclePrice) IsStale(bank *Bank, currentTimestamp int64) bool {
+	maxAge := int64(bank.Config.OracleMaxAge)
+	if maxAge == 0 {
+		maxAge = MaxPriceAgeSec
+	}
+	return currentTimestamp-p.Timestamp > maxAge
+}
+
+// WithRealtimePrice returns a copy of the map where banks are repriced with the realtime price,
+// time weighted price is kept as it barely moves within a slot
+func (m OraclePriceMap) WithRealtimePrice(banks []solana.PublicKey, price float64, conf float64) OraclePriceMap {
+	overridden := make(OraclePriceMap, len(m))
+	for bankPK, oraclePrice := range m {
+		overridden[bankPK] = oraclePrice
+	}
+	realtime := NewPriceWithConfidence(price, conf)
+	for _, bankPK := range banks {
+		oraclePrice := &OraclePrice{PriceRealtime: realtime, PriceWeighted: realtime}
+		if current := m[bankPK]; current != nil {
+			oraclePrice.PriceWeighted = current.PriceWeighted
+			oraclePrice.Timestamp = current.Timestamp
+		}
+		overridden[bankPK] = oraclePrice
+	}
+	return overridden
 }
 
 func GetPrice(oraclePrice *OraclePrice, bias PriceBias, isWeighted bool) (res fixed.I80F48) {
@@ -63,10 +174,16 @@ func GetPrice(oraclePrice *OraclePrice, bias PriceBias, isWeighted bool) (res fi
 	return
 }
 
+// GetPriceWithConfidence returns time weighted (ema) price when isWeighted is set, realtime price otherwise
 func GetPriceWithConfidence(oraclePrice *OraclePrice, isWeighted bool) PriceWithConfidence {
 	if isWeighted {
-		panic("is weighted price not really implemented... need to do ema parsing from pyth")
-		//return oraclePrice.PriceWeighted
+		return oraclePrice.PriceWeighted
 	}
 	return oraclePrice.PriceRealtime
 }
+
+// IsWeightedPrice tells which price the risk engine uses for the requirement,
+// only initial uses time weighted price, maintenance and equity use realtime price
+func IsWeightedPrice(reqType MarginRequirementType) bool {
+	return reqType == MarginRequirementTypeInitial
+}
@@ -0,0 +1,17 @@
+package marginfi
+
+import "testing"
+
+func TestOraclePriceIsStale(t *testing.T) {
+	price := &OraclePrice{Timestamp: 1_700_000_000}
+
+	bank := &Bank{}
+	if price.IsStale(bank, 1_700_000_000+MaxPriceAgeSec) || !price.IsStale(bank, 1_700_000_000+MaxPriceAgeSec+1) {
+		t.Fatal("bank without max age should use MaxPriceAgeSec")
+	}
+
+	bank.Config.OracleMaxAge = 300
+	if price.IsStale(bank, 1_700_000_300) || !price.IsStale(bank, 1_700_000_301) {
+		t.Fatal("bank max age should be used")
+	}
+}
@@ -0,0 +1,160 @@
+package marginfi
+
+import (
+	"errors"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrOraclePriceNotFound = errors.New("oracle price not found")
+
+type BalanceSide string
+
+const (
+	BalanceSideAsset     BalanceSide = "asset"
+	BalanceSideLiability BalanceSide = "liability"
+)
+
+// HealthFigures are weighted USD values for a margin requirement, Health is Assets - Liabilities
+type HealthFigures struct {
+	Assets      float64 `json:"assets"`
+	Liabilities float64 `json:"liabilities"`
+	Health      float64 `json:"health"`
+}
+
+type BalanceReport struct {
+	BankPK solana.PublicKey `json:"bank"`
+	Mint   solana.PublicKey `json:"mint"`
+	Symbol string           `json:"symbol"`
+	Side   BalanceSide      `json:"side"`
+	// Quantity in UI units (native / 10^decimals)
+	Quantity float64 `json:"quantity"`
+	// unweighted realtime USD value of the quantity under each price bias
+	UsdValueLowest  float64 `json:"usdValueLowest"`
+	UsdValueNone    float64 `json:"usdValueNone"`
+	UsdValueHighest float64 `json:"usdValueHighest"`
+
+	AssetWeightInit      float64 `json:"assetWeightInit"`
+	AssetWeightMaint     float64 `json:"assetWeightMaint"`
+	LiabilityWeightInit  float64 `json:"liabilityWeightInit"`
+	LiabilityWeightMaint float64 `json:"liabilityWeightMaint"`
+
+	// LiquidationPrice is the collateral price at which maintenance health reaches zero, other prices unchanged,
+	// nil for liabilities and collateral that can't make the account liquidatable on its own
+	LiquidationPrice *float64 `json:"liquidationPrice,omitempty"`
+}
+
+type AccountReport struct {
+	AccountPK   solana.PublicKey `json:"account"`
+	Authority   solana.PublicKey `json:"authority"`
+	Balances    []BalanceReport  `json:"balances"`
+	Initial     HealthFigures    `json:"initial"`
+	Maintenance HealthFigures    `json:"maintenance"`
+	Equity      HealthFigures    `json:"equity"`
+}
+
+// Report describes balances and health of the account, metadata is optional and only used for token symbols
+func (m *MarginfiAccount) Report(
+	accountPK solana.PublicKey, banks BankMap, oraclePrices OraclePriceMap, metadata map[solana.PublicKey]BankMetadata,
+) (*AccountReport, error) {
+	report := &AccountReport{
+		AccountPK: accountPK,
+		Authority: m.Authority,
+		Balances:  make([]BalanceReport, 0),
+	}
+
+	for _, balance := range m.LendingAccount.Balances {
+		if !balance.Active {
+			continue
+		}
+		bank := banks[balance.BankPK]
+		if bank == nil {
+			return nil, ErrBankNotFound
+		}
+		oraclePrice := oraclePrices[balance.BankPK]
+		if oraclePrice == nil {
+			return nil, ErrOraclePriceNotFound
+		}
+
+		side, quantity := BalanceSideAsset, bank.GetAssetQuantity(balance.AssetShares)
+		if balance.AssetShares.IsZero() && !balance.LiabilityShares.IsZero() {
+			side, quantity = BalanceSideLiability, bank.GetLiabilityQuantity(balance.LiabilityShares)
+		}
+
+		uiQuantity := quantity.AsFloat64() / math.Pow10(int(bank.MintDecimals))
+		symbol := bank.Mint.String()
+		if bankMetadata, ok := metadata[balance.BankPK]; ok && bankMetadata.TokenSymbol != "" {
+			symbol = bankMetadata.TokenSymbol
+		}
+
+		report.Balances = append(report.Balances, BalanceReport{
+			BankPK:   balance.BankPK,
+			Mint:     bank.Mint,
+			Symbol:   symbol,
+			Side:     side,
+			Quantity: uiQuantity,
+
+			UsdValueLowest:  uiQuantity * GetPrice(oraclePrice, PriceBiasLowest, false).AsFloat64(),
+			UsdValueNone:    uiQuantity * GetPrice(oraclePrice, PriceBiasNone, false).AsFloat64(),
+			UsdValueHighest: uiQuantity * GetPrice(oraclePrice, PriceBiasHighest, false).AsFloat64(),
+
+			AssetWeightInit:      bank.GetAssetWeight(MarginRequirementTypeInitial, oraclePrice).AsFloat64(),
+			AssetWeightMaint:     bank.GetAssetWeight(MarginRequirementTypeMaintenance, oraclePrice).AsFloat64(),
+			LiabilityWeightInit:  bank.GetLiabilityWeight(MarginRequirementTypeInitial).AsFloat64(),
+			LiabilityWeightMaint: bank.GetLiabilityWeight(MarginRequirementTypeMaintenance).AsFloat64(),
+		})
+	}
+
+	report.Initial = m.computeHealthFigures(banks, oraclePrices, MarginRequirementTypeInitial)
+	report.Maintenance = m.computeHealthFigures(banks, oraclePrices, MarginRequirementTypeMaintenance)
+	report.Equity = m.computeHealthFigures(banks, oraclePrices, MarginRequirementTypeEquity)
+
+	for i := range report.Balances {
+		if report.Balances[i].Side != BalanceSideAsset {
+			continue
+		}
+		if price, ok := m.ComputeLiquidationPrice(banks, oraclePrices, report.Balances[i].BankPK); ok {
+			report.Balances[i].LiquidationPrice = &price
+		}
+	}
+
+	return report, nil
+}
+
+func (m *MarginfiAccount) computeHealthFigures(banks BankMap, oraclePrices OraclePriceMap, req MarginRequirementType) HealthFigures {
+	assets, liabilities := m.ComputeHealthComponents(banks, oraclePrices, req)
+	return HealthFigures{
+		Assets:      assets.AsFloat64(),
+		Liabilities: liabilities.AsFloat64(),
+		Health:      assets.AsFloat64() - liabilities.AsFloat64(),
+	}
+}
+
+// ComputeLiquidationPrice solves maintenance assets == liabilities for the realtime price of the collateral bank,
+// all other prices are kept, confidence interval is assumed unchanged
+func (m *MarginfiAccount) ComputeLiquidationPrice(banks BankMap, oraclePrices OraclePriceMap, bankPK solana.PublicKey) (float64, bool) {
+	bank := banks[bankPK]
+	oraclePrice := oraclePrices[bankPK]
+	balance := m.GetBalance(bankPK)
+	if bank == nil || oraclePrice == nil || balance == nil || balance.AssetShares.IsZero() {
+		return 0, false
+	}
+
+	assets, liabilities := m.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeMaintenance)
+	collateral := bank.ComputeAssetUsdValue(oraclePrice, balance.AssetShares, MarginRequirementTypeMaintenance, PriceBiasLowest)
+	otherAssets := assets.AsFloat64() - collateral.AsFloat64()
+	shortfall := liabilities.AsFloat64() - otherAssets
+	if shortfall <= 0 {
+		return 0, false
+	}
+
+	weight := bank.GetAssetWeight(MarginRequirementTypeMaintenance, oraclePrice).AsFloat64()
+	uiQuantity := bank.GetAssetQuantity(balance.AssetShares).AsFloat64() / math.Pow10(int(bank.MintDecimals))
+	if weight <= 0 || uiQuantity <= 0 {
+		return 0, false
+	}
+
+	confInterval := oraclePrice.PriceRealtime.Price.AsFloat64() - oraclePrice.PriceRealtime.LowestPrice.AsFloat64()
+	return shortfall/(uiQuantity*weight) + confInterval, true
+}
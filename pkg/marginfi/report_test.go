@@ -0,0 +1,79 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestAccountReport(t *testing.T) {
+	solBankPK, usdcBankPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	banks := BankMap{
+		solBankPK: &Bank{
+			MintDecimals:        9,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightInit:      fixed.MustI80F48FromFloat64(0.8),
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(0.9),
+				LiabilityWeightInit:  fixed.MustI80F48FromFloat64(1.25),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+			},
+		},
+		usdcBankPK: &Bank{
+			MintDecimals:        6,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightInit:      fixed.MustI80F48FromFloat64(1),
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(1),
+				LiabilityWeightInit:  fixed.MustI80F48FromFloat64(1.25),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+			},
+		},
+	}
+	oraclePrices := OraclePriceMap{
+		solBankPK:  &OraclePrice{PriceRealtime: NewPriceWithConfidence(100, 0), PriceWeighted: NewPriceWithConfidence(100, 0)},
+		usdcBankPK: &OraclePrice{PriceRealtime: NewPriceWithConfidence(1, 0), PriceWeighted: NewPriceWithConfidence(1, 0)},
+	}
+
+	account := &MarginfiAccount{}
+	account.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e9)}
+	account.LendingAccount.Balances[1] = Balance{Active: true, BankPK: usdcBankPK, LiabilityShares: fixed.MustI80F48FromFloat64(70e6)}
+
+	report, err := account.Report(solana.PublicKey{}, banks, oraclePrices, map[solana.PublicKey]BankMetadata{
+		solBankPK: {TokenSymbol: "SOL"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Balances) != 2 {
+		t.Fatalf("expected 2 balances, got %d", len(report.Balances))
+	}
+
+	sol, usdc := report.Balances[0], report.Balances[1]
+	if sol.Symbol != "SOL" || sol.Side != BalanceSideAsset || math.Abs(sol.Quantity-1) > 1e-9 || math.Abs(sol.UsdValueNone-100) > 1e-6 {
+		t.Fatalf("unexpected sol balance %+v", sol)
+	}
+	if usdc.Side != BalanceSideLiability || math.Abs(usdc.Quantity-70) > 1e-6 || usdc.LiquidationPrice != nil {
+		t.Fatalf("unexpected usdc balance %+v", usdc)
+	}
+	if math.Abs(report.Maintenance.Health-(90-77)) > 1e-6 || math.Abs(report.Equity.Health-30) > 1e-6 || math.Abs(report.Initial.Health-(80-87.5)) > 1e-6 {
+		t.Fatalf("unexpected health %+v %+v %+v", report.Initial, report.Maintenance, report.Equity)
+	}
+
+	if sol.LiquidationPrice == nil || math.Abs(*sol.LiquidationPrice-77/0.9) > 1e-6 {
+		t.Fatalf("unexpected liquidation price %v", sol.LiquidationPrice)
+	}
+	shocked := oraclePrices.WithRealtimePrice([]solana.PublicKey{solBankPK}, *sol.LiquidationPrice-0.01, 0)
+	assets, liabilities := account.ComputeHealthComponents(banks, shocked, MarginRequirementTypeMaintenance)
+	if !assets.LessThan(liabilities) {
+		t.Fatal("account should be liquidatable below liquidation price")
+	}
+
+	if _, err := account.Report(solana.PublicKey{}, BankMap{solBankPK: banks[solBankPK]}, oraclePrices, nil); err != ErrBankNotFound {
+		t.Fatalf("expected ErrBankNotFound, got %v", err)
+	}
+}
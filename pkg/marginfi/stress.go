@@ -0,0 +1,217 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// DefaultHealthFactorBuckets upper bounds of maintenance health factor (assets / liabilities) buckets,
+// last bucket is open ended
+var DefaultHealthFactorBuckets = []float64{1, 1.05, 1.1, 1.25, 1.5, 2}
+
+// PriceShock moves prices of the banks by relative change, -0.1 is a 10% drop
+type PriceShock struct {
+	Name    string
+	Changes map[solana.PublicKey]float64
+}
+
+// NewPriceShockBySymbol resolves token symbols (case insensitive) to every bank of that token
+func NewPriceShockBySymbol(name string, changes map[string]float64, banks BankMap, metadata map[solana.PublicKey]BankMetadata) PriceShock {
+	shock := PriceShock{Name: name, Changes: make(map[solana.PublicKey]float64)}
+	for bankPK := range banks {
+		bankMetadata, ok := metadata[bankPK]
+		if !ok {
+			continue
+		}
+		for symbol, change := range changes {
+			if strings.EqualFold(bankMetadata.TokenSymbol, symbol) {
+				shock.Changes[bankPK] = change
+			}
+		}
+	}
+	return shock
+}
+
+type BankExposure struct {
+	BankPK         solana.PublicKey `json:"bank"`
+	Symbol         string           `json:"symbol"`
+	Accounts       int              `json:"accounts"`
+	AssetsUsd      float64          `json:"assetsUsd"`
+	LiabilitiesUsd float64          `json:"liabilitiesUsd"`
+}
+
+type HealthBucket struct {
+	// UpperBound of the health factor, zero for the last open ended bucket
+	UpperBound     float64 `json:"upperBound,omitempty"`
+	Accounts       int     `json:"accounts"`
+	LiabilitiesUsd float64 `json:"liabilitiesUsd"`
+}
+
+type ShockResult struct {
+	Name                 string  `json:"name"`
+	LiquidatableAccounts int     `json:"liquidatableAccounts"`
+	LiabilitiesUsd       float64 `json:"liabilitiesUsd"`
+	// LiquidatableAssetUsd collateral the solver would seize, this is flashloan size liquidator needs
+	LiquidatableAssetUsd float64 `json:"liquidatableAssetUsd"`
+	ProfitUsd            float64 `json:"profitUsd"`
+}
+
+type GroupRiskReport struct {
+	Accounts int `json:"accounts"`
+	// Skipped accounts with a balance in a bank that is unknown or has no valid price, they are left out of
+	// health distribution and shocks since their health can't be computed
+	Skipped            int            `json:"skipped"`
+	Exposure           []BankExposure `json:"exposure"`
+	HealthDistribution []HealthBucket `json:"healthDistribution"`
+	Shocks             []ShockResult  `json:"shocks"`
+}
+
+// WithShock returns a copy of the map with realtime and weighted prices of shocked banks scaled,
+// confidence is scaled as well so relative uncertainty is kept
+func (m OraclePriceMap) WithShock(shock PriceShock) OraclePriceMap {
+	shocked := make(OraclePriceMap, len(m))
+	for bankPK, oraclePrice := range m {
+		shocked[bankPK] = oraclePrice
+	}
+	for bankPK, change := range shock.Changes {
+		current := m[bankPK]
+		if current == nil {
+			continue
+		}
+		multiplier := fixed.MustI80F48FromFloat64(math.Max(0, 1+change))
+		shocked[bankPK] = &OraclePrice{
+			PriceRealtime: current.PriceRealtime.scaled(multiplier),
+			PriceWeighted: current.PriceWeighted.scaled(multiplier),
+			Timestamp:     current.Timestamp,
+		}
+	}
+	return shocked
+}
+
+func (p PriceWithConfidence) scaled(multiplier fixed.I80F48) PriceWithConfidence {
+	return PriceWithConfidence{
+		Price:        p.Price.Mul(multiplier),
+		Conf:         p.Conf.Mul(multiplier),
+		LowestPrice:  p.LowestPrice.Mul(multiplier),
+		HighestPrice: p.HighestPrice.Mul(multiplier),
+	}
+}
+
+// AnalyzeGroupRisk aggregates exposure per bank, maintenance health factor distribution and
+// liquidatable liabilities under each shock, metadata is optional and only used for symbols
+func AnalyzeGroupRisk(
+	accounts map[solana.PublicKey]*MarginfiAccount,
+	banks BankMap,
+	oraclePrices OraclePriceMap,
+	shocks []PriceShock,
+	metadata map[solana.PublicKey]BankMetadata,
+) *GroupRiskReport {
+	priced := make(map[solana.PublicKey]*MarginfiAccount, len(accounts))
+	for accountPK, account := range accounts {
+		if isPriced(account, banks, oraclePrices) {
+			priced[accountPK] = account
+		}
+	}
+
+	report := &GroupRiskReport{
+		Accounts:           len(accounts),
+		Skipped:            len(accounts) - len(priced),
+		Exposure:           computeExposure(accounts, banks, oraclePrices, metadata),
+		HealthDistribution: computeHealthDistribution(priced, banks, oraclePrices, DefaultHealthFactorBuckets),
+		Shocks:             make([]ShockResult, 0, len(shocks)),
+	}
+	for _, shock := range shocks {
+		report.Shocks = append(report.Shocks, computeShockResult(priced, banks, oraclePrices.WithShock(shock), shock.Name))
+	}
+	return report
+}
+
+// isPriced tells if every active balance has its bank and price, ComputeHealthComponents returns zero otherwise
+func isPriced(account *MarginfiAccount, banks BankMap, oraclePrices OraclePriceMap) bool {
+	for _, balance := range account.LendingAccount.Balances {
+		if balance.Active && (banks[balance.BankPK] == nil || oraclePrices[balance.BankPK] == nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func computeExposure(
+	accounts map[solana.PublicKey]*MarginfiAccount, banks BankMap, oraclePrices OraclePriceMap, metadata map[solana.PublicKey]BankMetadata,
+) []BankExposure {
+	exposures := make(map[solana.PublicKey]*BankExposure)
+	for _, account := range accounts {
+		for _, balance := range account.LendingAccount.Balances {
+			bank, oraclePrice := banks[balance.BankPK], oraclePrices[balance.BankPK]
+			if !balance.Active || bank == nil || oraclePrice == nil {
+				continue
+			}
+			exposure, ok := exposures[balance.BankPK]
+			if !ok {
+				symbol := bank.Mint.String()
+				if bankMetadata, ok := metadata[balance.BankPK]; ok && bankMetadata.TokenSymbol != "" {
+					symbol = bankMetadata.TokenSymbol
+				}
+				exposure = &BankExposure{BankPK: balance.BankPK, Symbol: symbol}
+				exposures[balance.BankPK] = exposure
+			}
+			exposure.Accounts++
+			exposure.AssetsUsd += bank.ComputeUsdValue(oraclePrice, bank.GetAssetQuantity(balance.AssetShares), PriceBiasNone, false, fixed.I80f48One, true).AsFloat64()
+			exposure.LiabilitiesUsd += bank.ComputeUsdValue(oraclePrice, bank.GetLiabilityQuantity(balance.LiabilityShares), PriceBiasNone, false, fixed.I80f48One, true).AsFloat64()
+		}
+	}
+
+	result := make([]BankExposure, 0, len(exposures))
+	for _, exposure := range exposures {
+		result = append(result, *exposure)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AssetsUsd > result[j].AssetsUsd })
+	return result
+}
+
+// computeHealthDistribution only accounts with liabilities have a health factor
+func computeHealthDistribution(
+	accounts map[solana.PublicKey]*MarginfiAccount, banks BankMap, oraclePrices OraclePriceMap, upperBounds []float64,
+) []HealthBucket {
+	buckets := make([]HealthBucket, len(upperBounds)+1)
+	for i, upperBound := range upperBounds {
+		buckets[i].UpperBound = upperBound
+	}
+
+	for _, account := range accounts {
+		assets, liabilities := account.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeMaintenance)
+		if liabilities.IsZero() {
+			continue
+		}
+		healthFactor := assets.AsFloat64() / liabilities.AsFloat64()
+		i := sort.Search(len(upperBounds), func(i int) bool { return healthFactor < upperBounds[i] })
+		buckets[i].Accounts++
+		buckets[i].LiabilitiesUsd += liabilities.AsFloat64()
+	}
+	return buckets
+}
+
+func computeShockResult(accounts map[solana.PublicKey]*MarginfiAccount, banks BankMap, oraclePrices OraclePriceMap, name string) ShockResult {
+	result := ShockResult{Name: name}
+	for _, account := range accounts {
+		assets, liabilities := account.ComputeHealthComponents(banks, oraclePrices, MarginRequirementTypeMaintenance)
+		if !assets.LessThan(liabilities) {
+			continue
+		}
+		result.LiquidatableAccounts++
+		result.LiabilitiesUsd += liabilities.AsFloat64()
+
+		plan, ok := account.FindBestLiquidation(banks, oraclePrices, LiquidationSolverOptions{SafetyMargin: DefaultLiquidationSafetyMargin})
+		if !ok {
+			continue
+		}
+		assetPrice := GetPrice(oraclePrices[plan.AssetBankPK], PriceBiasNone, false).AsFloat64()
+		result.LiquidatableAssetUsd += float64(plan.AssetAmount) / math.Pow10(int(banks[plan.AssetBankPK].MintDecimals)) * assetPrice
+		result.ProfitUsd += plan.ProfitUsd
+	}
+	return result
+}
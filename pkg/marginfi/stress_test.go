@@ -0,0 +1,133 @@
+package marginfi
+
+import (
+	"jito-bot/pkg/fixed"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestAnalyzeGroupRisk(t *testing.T) {
+	solBankPK, usdcBankPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	banks := BankMap{
+		solBankPK: &Bank{
+			MintDecimals:        9,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(0.9),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+				OperationalState:     BankOperationalStateOperational,
+			},
+		},
+		usdcBankPK: &Bank{
+			MintDecimals:        6,
+			AssetShareValue:     fixed.I80f48One,
+			LiabilityShareValue: fixed.I80f48One,
+			Config: BankConfig{
+				AssetWeightMaint:     fixed.MustI80F48FromFloat64(1),
+				LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+				OperationalState:     BankOperationalStateOperational,
+			},
+		},
+	}
+	oraclePrices := OraclePriceMap{
+		solBankPK:  &OraclePrice{PriceRealtime: NewPriceWithConfidence(100, 0), PriceWeighted: NewPriceWithConfidence(100, 0)},
+		usdcBankPK: &OraclePrice{PriceRealtime: NewPriceWithConfidence(1, 0), PriceWeighted: NewPriceWithConfidence(1, 0)},
+	}
+	metadata := map[solana.PublicKey]BankMetadata{solBankPK: {TokenSymbol: "SOL"}, usdcBankPK: {TokenSymbol: "USDC"}}
+
+	// health factors 90/77 = 1.17 and 90/55 = 1.64
+	risky, safe := &MarginfiAccount{}, &MarginfiAccount{}
+	risky.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e9)}
+	risky.LendingAccount.Balances[1] = Balance{Active: true, BankPK: usdcBankPK, LiabilityShares: fixed.MustI80F48FromFloat64(70e6)}
+	safe.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e9)}
+	safe.LendingAccount.Balances[1] = Balance{Active: true, BankPK: usdcBankPK, LiabilityShares: fixed.MustI80F48FromFloat64(50e6)}
+	lender := &MarginfiAccount{}
+	lender.LendingAccount.Balances[0] = Balance{Active: true, BankPK: usdcBankPK, AssetShares: fixed.MustI80F48FromFloat64(500e6)}
+	accounts := map[solana.PublicKey]*MarginfiAccount{
+		solana.NewWallet().PublicKey(): risky,
+		solana.NewWallet().PublicKey(): safe,
+		solana.NewWallet().PublicKey(): lender,
+	}
+
+	shocks := []PriceShock{
+		NewPriceShockBySymbol("SOL -10%", map[string]float64{"sol": -0.1}, banks, metadata),
+		NewPriceShockBySymbol("SOL -20%", map[string]float64{"SOL": -0.2}, banks, metadata),
+	}
+	report := AnalyzeGroupRisk(accounts, banks, oraclePrices, shocks, metadata)
+
+	if report.Accounts != 3 || len(report.Exposure) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Exposure[0].Symbol != "USDC" || math.Abs(report.Exposure[0].AssetsUsd-500) > 1e-6 || math.Abs(report.Exposure[0].LiabilitiesUsd-120) > 1e-6 {
+		t.Fatalf("unexpected usdc exposure %+v", report.Exposure[0])
+	}
+	if report.Exposure[1].Accounts != 2 || math.Abs(report.Exposure[1].AssetsUsd-200) > 1e-6 {
+		t.Fatalf("unexpected sol exposure %+v", report.Exposure[1])
+	}
+
+	// lender has no health factor
+	if report.HealthDistribution[3].Accounts != 1 || report.HealthDistribution[4].Accounts != 0 || report.HealthDistribution[5].Accounts != 1 {
+		t.Fatalf("unexpected health distribution %+v", report.HealthDistribution)
+	}
+
+	// risky maintenance assets are 81 at -10% and 72 at -20% against 77 of liabilities
+	if report.Shocks[0].LiquidatableAccounts != 0 {
+		t.Fatalf("nothing should be liquidatable at -10%%, got %+v", report.Shocks[0])
+	}
+	if report.Shocks[1].LiquidatableAccounts != 1 || math.Abs(report.Shocks[1].LiabilitiesUsd-77) > 1e-6 || report.Shocks[1].ProfitUsd <= 0 {
+		t.Fatalf("risky account should be liquidatable at -20%%, got %+v", report.Shocks[1])
+	}
+
+	if price := GetPrice(oraclePrices[solBankPK], PriceBiasNone, false).AsFloat64(); price != 100 {
+		t.Fatalf("shock must not modify original prices, got %v", price)
+	}
+}
+
+func TestAnalyzeGroupRiskSkipsUnpricedAccounts(t *testing.T) {
+	solBankPK, usdcBankPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	bank := &Bank{
+		MintDecimals:        6,
+		AssetShareValue:     fixed.I80f48One,
+		LiabilityShareValue: fixed.I80f48One,
+		Config: BankConfig{
+			AssetWeightMaint:     fixed.MustI80F48FromFloat64(1),
+			LiabilityWeightMaint: fixed.MustI80F48FromFloat64(1.1),
+			OperationalState:     BankOperationalStateOperational,
+		},
+	}
+	banks := BankMap{solBankPK: bank, usdcBankPK: bank}
+	// sol price is stale
+	oraclePrices := OraclePriceMap{
+		usdcBankPK: &OraclePrice{PriceRealtime: NewPriceWithConfidence(1, 0), PriceWeighted: NewPriceWithConfidence(1, 0)},
+	}
+
+	borrower := &MarginfiAccount{}
+	borrower.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solBankPK, AssetShares: fixed.MustI80F48FromFloat64(1e6)}
+	borrower.LendingAccount.Balances[1] = Balance{Active: true, BankPK: usdcBankPK, LiabilityShares: fixed.MustI80F48FromFloat64(70e6)}
+	unknownBank := &MarginfiAccount{}
+	unknownBank.LendingAccount.Balances[0] = Balance{Active: true, BankPK: solana.NewWallet().PublicKey(), AssetShares: fixed.MustI80F48FromFloat64(1e6)}
+	lender := &MarginfiAccount{}
+	lender.LendingAccount.Balances[0] = Balance{Active: true, BankPK: usdcBankPK, AssetShares: fixed.MustI80F48FromFloat64(500e6)}
+	accounts := map[solana.PublicKey]*MarginfiAccount{
+		solana.NewWallet().PublicKey(): borrower,
+		solana.NewWallet().PublicKey(): unknownBank,
+		solana.NewWallet().PublicKey(): lender,
+	}
+
+	shocks := []PriceShock{{Name: "USDC +50%", Changes: map[solana.PublicKey]float64{usdcBankPK: 0.5}}}
+	report := AnalyzeGroupRisk(accounts, banks, oraclePrices, shocks, nil)
+	if report.Accounts != 3 || report.Skipped != 2 {
+		t.Fatalf("borrower and unknown bank account should be skipped, got %+v", report)
+	}
+	for _, bucket := range report.HealthDistribution {
+		if bucket.Accounts != 0 {
+			t.Fatalf("skipped accounts must not have health factor, got %+v", report.HealthDistribution)
+		}
+	}
+	if report.Shocks[0].LiquidatableAccounts != 0 {
+		t.Fatalf("skipped accounts must not be liquidatable, got %+v", report.Shocks[0])
+	}
+}
@@ -3,6 +3,7 @@ package me
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
@@ -13,11 +14,58 @@ var M3ProgramAddress = solana.MustPublicKeyFromBase58("M3mxk5W2tt27WGT7THox7PmgR
 
 const TakerFee = 0.025 // 2.5%
 
-var M2SellerTradeStateV2Discriminator = [...]byte{
-	0xa4, 0x0e, 0x5c, 0x64, 0x7b, 0x39, 0xea, 0xcc,
+var (
+	ErrInvalidDiscriminator = errors.New("me: invalid account discriminator")
+	ErrInvalidAccountSize   = errors.New("me: account data is too short")
+)
+
+// DecodeStats counts accounts FindAll* skipped instead of failing the whole scan, accounts are queried
+// by the discriminator of every known version so only malformed ones are skipped
+type DecodeStats struct {
+	Decoded int
+	// Malformed accounts are too short or failed to decode
+	Malformed int
 }
 
+func (s *DecodeStats) add(err error) {
+	if err != nil {
+		s.Malformed++
+		return
+	}
+	s.Decoded++
+}
+
+// checkAccount discriminator and minimal size, accounts may be allocated bigger than their layout
+func checkAccount(data []byte, discriminator [8]byte, size int) error {
+	if len(data) < 8 || [8]byte(data[:8]) != discriminator {
+		return ErrInvalidDiscriminator
+	}
+	if len(data) < size {
+		return ErrInvalidAccountSize
+	}
+	return nil
+}
+
+var (
+	// M2SellerTradeStateV1Discriminator legacy listings without payment mint, always paid in SOL
+	M2SellerTradeStateV1Discriminator = [...]byte{
+		0x01, 0xee, 0x48, 0x89, 0x8a, 0x15, 0xfe, 0xf9,
+	}
+	M2SellerTradeStateV2Discriminator = [...]byte{
+		0xa4, 0x0e, 0x5c, 0x64, 0x7b, 0x39, 0xea, 0xcc,
+	}
+)
+
+const (
+	// M2SellerTradeStateV1Size discriminator + 3 keys + price + mint + token account + size + bump + expiry
+	M2SellerTradeStateV1Size = 8 + 32*3 + 8 + 32 + 32 + 8 + 1 + 8
+	M2SellerTradeStateV2Size = M2SellerTradeStateV1Size + 32 // + payment mint
+)
+
 type M2SellerTradeState struct {
+	Address solana.PublicKey // not part of the account data
+	Version uint8            // not part of the account data, 1 or 2
+
 	AuctionHouseKey solana.PublicKey
 	Seller          solana.PublicKey
 	SellerReferral  solana.PublicKey
@@ -27,55 +75,79 @@ type M2SellerTradeState struct {
 	TokenSize       uint64
 	// bump u8
 	Expiry      int64
-	PaymentMint solana.PublicKey
+	PaymentMint solana.PublicKey // zero for SOL and version 1
 }
 
-func ParseM2SellerTradeState(data []byte) *M2SellerTradeState {
+// ParseM2SellerTradeState decodes both versions of the listing, ErrInvalidDiscriminator for other accounts
+func ParseM2SellerTradeState(address solana.PublicKey, data []byte) (*M2SellerTradeState, error) {
+	version := uint8(2)
+	if err := checkAccount(data, M2SellerTradeStateV2Discriminator, M2SellerTradeStateV2Size); err != nil {
+		if err != ErrInvalidDiscriminator {
+			return nil, err
+		}
+		if err := checkAccount(data, M2SellerTradeStateV1Discriminator, M2SellerTradeStateV1Size); err != nil {
+			return nil, err
+		}
+		version = 1
+	}
+
 	data = data[8:] // skip discriminator
-	return &M2SellerTradeState{
-		AuctionHouseKey: solana.PublicKey(data[0:32]),
-		Seller:          solana.PublicKey(data[32:64]),
-		SellerReferral:  solana.PublicKey(data[64:96]),
+	tradeState := &M2SellerTradeState{
+		Address:         address,
+		Version:         version,
+		AuctionHouseKey: solana.PublicKeyFromBytes(data[0:32]),
+		Seller:          solana.PublicKeyFromBytes(data[32:64]),
+		SellerReferral:  solana.PublicKeyFromBytes(data[64:96]),
 		BuyerPrice:      binary.LittleEndian.Uint64(data[96:104]),
-		TokenMint:       solana.PublicKey(data[104:136]),
-		TokenAccount:    solana.PublicKey(data[136:168]),
+		TokenMint:       solana.PublicKeyFromBytes(data[104:136]),
+		TokenAccount:    solana.PublicKeyFromBytes(data[136:168]),
 		TokenSize:       binary.LittleEndian.Uint64(data[168:176]),
 		Expiry:          int64(binary.LittleEndian.Uint64(data[177:185])),
-		PaymentMint:     solana.PublicKey(data[185:217]),
 	}
+	if version == 2 {
+		tradeState.PaymentMint = solana.PublicKeyFromBytes(data[185:217])
+	}
+	return tradeState, nil
 }
 
-func FindAllM2SellterTradeStates(connection *rpc.Client) ([]*M2SellerTradeState, error) {
-	gpa, err := connection.GetProgramAccountsWithOpts(context.Background(), M2ProgramAddress, &rpc.GetProgramAccountsOpts{
-		Commitment: rpc.CommitmentFinalized,
-		Filters: []rpc.RPCFilter{{
-			Memcmp: &rpc.RPCFilterMemcmp{
-				Offset: 0,
-				Bytes:  M2SellerTradeStateV2Discriminator[:],
-			},
-		}},
-	})
-	if err != nil {
-		return nil, err
-	}
+// IsExpired listings without expiry have it zero or negative
+func (s *M2SellerTradeState) IsExpired(now int64) bool {
+	return s.Expiry > 0 && s.Expiry < now
+}
 
-	sellerTradeStates := make([]*M2SellerTradeState, 0, len(gpa))
-	for _, acc := range gpa {
-		tradeState := ParseM2SellerTradeState(acc.Account.Data.GetBinary())
+// FindAllM2SellterTradeStates loads listings of all versions, malformed accounts are skipped and counted
+func FindAllM2SellterTradeStates(connection *rpc.Client) ([]*M2SellerTradeState, DecodeStats, error) {
+	var stats DecodeStats
+	sellerTradeStates := make([]*M2SellerTradeState, 0)
+	for _, discriminator := range [][8]byte{M2SellerTradeStateV2Discriminator, M2SellerTradeStateV1Discriminator} {
+		gpa, err := findAllByDiscriminator(connection, M2ProgramAddress, discriminator)
 		if err != nil {
-			return nil, err
+			return nil, stats, err
+		}
+
+		for _, acc := range gpa {
+			tradeState, err := ParseM2SellerTradeState(acc.Pubkey, acc.Account.Data.GetBinary())
+			stats.add(err)
+			if err != nil {
+				continue
+			}
+			sellerTradeStates = append(sellerTradeStates, tradeState)
 		}
-		sellerTradeStates = append(sellerTradeStates, tradeState)
 	}
 
-	return sellerTradeStates, nil
+	return sellerTradeStates, stats, nil
 }
 
 var M3SellerTradeDiscriminator = [...]byte{
 	0x01, 0xee, 0x48, 0x89, 0x8a, 0x15, 0xfe, 0xf9,
 }
 
+// M3SellerTradeStateSize discriminator + 2 keys + price + asset + payment mint + padding + tree + index + 2 timestamps
+const M3SellerTradeStateSize = 8 + 32*2 + 8 + 32 + 32 + 1 + 32 + 4 + 8 + 8
+
 type M3SellerTradeState struct {
+	Address solana.PublicKey // not part of the account data
+
 	Seller         solana.PublicKey
 	SellerReferral solana.PublicKey
 	BuyerPrice     uint64
@@ -88,43 +160,55 @@ type M3SellerTradeState struct {
 	UpdatedAt  int64
 }
 
-func ParseM3SellerTradeState(data []byte) *M3SellerTradeState {
+func ParseM3SellerTradeState(address solana.PublicKey, data []byte) (*M3SellerTradeState, error) {
+	if err := checkAccount(data, M3SellerTradeDiscriminator, M3SellerTradeStateSize); err != nil {
+		return nil, err
+	}
+
 	data = data[8:] // skip discriminator
 	return &M3SellerTradeState{
-		Seller:         solana.PublicKey(data[0:32]),
-		SellerReferral: solana.PublicKey(data[32:64]),
+		Address:        address,
+		Seller:         solana.PublicKeyFromBytes(data[0:32]),
+		SellerReferral: solana.PublicKeyFromBytes(data[32:64]),
 		BuyerPrice:     binary.LittleEndian.Uint64(data[64:72]),
-		AssetId:        solana.PublicKey(data[72:104]),
-		PaymentMint:    solana.PublicKey(data[104:136]),
-		MerkleTree:     solana.PublicKey(data[137:169]),
+		AssetId:        solana.PublicKeyFromBytes(data[72:104]),
+		PaymentMint:    solana.PublicKeyFromBytes(data[104:136]),
+		MerkleTree:     solana.PublicKeyFromBytes(data[137:169]),
 		Index:          binary.LittleEndian.Uint32(data[169:173]),
 		CreatedAt:      int64(binary.LittleEndian.Uint64(data[173:181])),
 		UpdatedAt:      int64(binary.LittleEndian.Uint64(data[181:189])),
-	}
+	}, nil
 }
 
-func FindAllM3SellterTradeStates(connection *rpc.Client) ([]*M3SellerTradeState, error) {
-	gpa, err := connection.GetProgramAccountsWithOpts(context.Background(), M3ProgramAddress, &rpc.GetProgramAccountsOpts{
-		Commitment: rpc.CommitmentFinalized,
-		Filters: []rpc.RPCFilter{{
-			Memcmp: &rpc.RPCFilterMemcmp{
-				Offset: 0,
-				Bytes:  M3SellerTradeDiscriminator[:],
-			},
-		}},
-	})
+// FindAllM3SellterTradeStates malformed accounts are skipped and counted
+func FindAllM3SellterTradeStates(connection *rpc.Client) ([]*M3SellerTradeState, DecodeStats, error) {
+	var stats DecodeStats
+	gpa, err := findAllByDiscriminator(connection, M3ProgramAddress, M3SellerTradeDiscriminator)
 	if err != nil {
-		return nil, err
+		return nil, stats, err
 	}
 
 	sellerTradeStates := make([]*M3SellerTradeState, 0, len(gpa))
 	for _, acc := range gpa {
-		wl := ParseM3SellerTradeState(acc.Account.Data.GetBinary())
+		tradeState, err := ParseM3SellerTradeState(acc.Pubkey, acc.Account.Data.GetBinary())
+		stats.add(err)
 		if err != nil {
-			return nil, err
+			continue
 		}
-		sellerTradeStates = append(sellerTradeStates, wl)
+		sellerTradeStates = append(sellerTradeStates, tradeState)
 	}
 
-	return sellerTradeStates, nil
+	return sellerTradeStates, stats, nil
+}
+
+func findAllByDiscriminator(connection *rpc.Client, program solana.PublicKey, discriminator [8]byte) (rpc.GetProgramAccountsResult, error) {
+	return connection.GetProgramAccountsWithOpts(context.Background(), program, &rpc.GetProgramAccountsOpts{
+		Commitment: rpc.CommitmentFinalized,
+		Filters: []rpc.RPCFilter{{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: 0,
+				Bytes:  discriminator[:],
+			},
+		}},
+	})
 }
@@ -0,0 +1,89 @@
+package me
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func newTestM2SellerTradeState(discriminator [8]byte, size int, seller, paymentMint solana.PublicKey) []byte {
+	data := make([]byte, size)
+	copy(data, discriminator[:])
+	copy(data[8+32:], seller[:])
+	binary.LittleEndian.PutUint64(data[8+96:], 2_000_000_000)
+	binary.LittleEndian.PutUint64(data[8+176:], 1)
+	expiry := int64(-1)
+	binary.LittleEndian.PutUint64(data[8+177:], uint64(expiry))
+	if size >= M2SellerTradeStateV2Size {
+		copy(data[8+185:], paymentMint[:])
+	}
+	return data
+}
+
+func TestParseM2SellerTradeState(t *testing.T) {
+	address, seller, paymentMint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	v2, err := ParseM2SellerTradeState(address, newTestM2SellerTradeState(M2SellerTradeStateV2Discriminator, M2SellerTradeStateV2Size, seller, paymentMint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2.Version != 2 || v2.Address != address || v2.Seller != seller || v2.PaymentMint != paymentMint {
+		t.Fatalf("unexpected v2 listing %+v", v2)
+	}
+	if v2.BuyerPrice != 2_000_000_000 || v2.Expiry != -1 {
+		t.Fatalf("unexpected v2 price or expiry %+v", v2)
+	}
+
+	// accounts may be allocated with extra space
+	v1, err := ParseM2SellerTradeState(address, newTestM2SellerTradeState(M2SellerTradeStateV1Discriminator, M2SellerTradeStateV1Size+16, seller, paymentMint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Version != 1 || v1.Seller != seller || !v1.PaymentMint.IsZero() || v1.Expiry != -1 {
+		t.Fatalf("unexpected v1 listing %+v", v1)
+	}
+
+	short := newTestM2SellerTradeState(M2SellerTradeStateV2Discriminator, M2SellerTradeStateV2Size, seller, paymentMint)[:M2SellerTradeStateV1Size]
+	if _, err := ParseM2SellerTradeState(address, short); err != ErrInvalidAccountSize {
+		t.Fatalf("got %v for short account", err)
+	}
+	if _, err := ParseM2SellerTradeState(address, MMMPoolDiscriminator[:]); err != ErrInvalidDiscriminator {
+		t.Fatalf("got %v for other account", err)
+	}
+	if _, err := ParseM2SellerTradeState(address, nil); err != ErrInvalidDiscriminator {
+		t.Fatalf("got %v for empty account", err)
+	}
+}
+
+func TestParseM3SellerTradeState(t *testing.T) {
+	address, assetId, tree := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	data := make([]byte, M3SellerTradeStateSize)
+	copy(data, M3SellerTradeDiscriminator[:])
+	copy(data[8+72:], assetId[:])
+	copy(data[8+137:], tree[:])
+	binary.LittleEndian.PutUint32(data[8+169:], 42)
+	binary.LittleEndian.PutUint64(data[8+181:], 1_700_000_000)
+
+	listing, err := ParseM3SellerTradeState(address, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Address != address || listing.AssetId != assetId || listing.MerkleTree != tree || listing.Index != 42 || listing.UpdatedAt != 1_700_000_000 {
+		t.Fatalf("unexpected listing %+v", listing)
+	}
+
+	if _, err := ParseM3SellerTradeState(address, data[:M3SellerTradeStateSize-1]); err != ErrInvalidAccountSize {
+		t.Fatalf("got %v for short account", err)
+	}
+}
+
+func TestDecodeStats(t *testing.T) {
+	var stats DecodeStats
+	for _, err := range []error{nil, nil, ErrInvalidDiscriminator, ErrInvalidAccountSize} {
+		stats.add(err)
+	}
+	if stats.Decoded != 2 || stats.Malformed != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,368 @@
+package me
+
+import (
+	"errors"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	M2AuctionHouse          = solana.MustPublicKeyFromBase58("E8cU1WiRWjanGxmn96ewBgk9vPTcL6AEZ1t6F6fkgUWe")
+	M2AuctionHouseAuthority = solana.MustPublicKeyFromBase58("autMW8SgBkVYeBgqYiTuJZnkvDZMVU2MHJh9Jh7CSQ2")
+	M2Notary                = solana.MustPublicKeyFromBase58("NTYeYJ1wr4bpM5xo6zx5En44SvJFAd35zTxxNoERYqd")
+
+	TokenMetadataProgramAddress      = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s")
+	AuthorizationRulesProgramAddress = solana.MustPublicKeyFromBase58("auth9SigNpDKz4sJJ1DfCTuZrZNSAgh9sFD3rboVmgg")
+)
+
+var (
+	M2BuyV2Discriminator = [...]byte{
+		0xb8, 0x17, 0xee, 0x61, 0x67, 0xc5, 0xd3, 0x3d,
+	}
+	M2ExecuteSaleV2Discriminator = [...]byte{
+		0x5b, 0xdc, 0x31, 0xdf, 0xcc, 0x81, 0x35, 0xc1,
+	}
+	M2Mip1ExecuteSaleV2Discriminator = [...]byte{
+		0xec, 0xa3, 0xcc, 0xad, 0x47, 0x90, 0xeb, 0x76,
+	}
+)
+
+var (
+	ErrNilListing             = errors.New("me: listing is nil")
+	ErrUnsupportedPaymentMint = errors.New("me: listing is not paid in SOL")
+	ErrListingExpired         = errors.New("me: listing is expired")
+)
+
+var (
+	m2Seed          = []byte("m2")
+	m2SignerSeed    = []byte("signer")
+	m2TreasurySeed  = []byte("treasury")
+	metadataSeed    = []byte("metadata")
+	editionSeed     = []byte("edition")
+	tokenRecordSeed = []byte("token_record")
+)
+
+const (
+	M2BuyV2InstructionSize             = 8 + 8 + 8 + 8 + 2 + 4 // buyer price + token size + expiry + royalty bp + empty extra args
+	M2ExecuteSaleV2InstructionSize     = 8 + 1 + 1 + 8 + 8 + 8 + 8 + 2 + 2
+	M2Mip1ExecuteSaleV2InstructionSize = 8 + 8 + 2 + 2 // price + maker fee bp + taker fee bp
+	// M2TakerFeeBp is TakerFee in basis points
+	M2TakerFeeBp uint16 = 250
+)
+
+func FindM2EscrowPaymentAccount(auctionHouse solana.PublicKey, wallet solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{m2Seed, auctionHouse.Bytes(), wallet.Bytes()}, M2ProgramAddress)
+}
+
+func FindM2BuyerTradeState(wallet solana.PublicKey, auctionHouse solana.PublicKey, tokenMint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{m2Seed, wallet.Bytes(), auctionHouse.Bytes(), tokenMint.Bytes()}, M2ProgramAddress)
+}
+
+func FindM2SellerTradeState(
+	wallet solana.PublicKey, auctionHouse solana.PublicKey, tokenAccount solana.PublicKey, tokenMint solana.PublicKey,
+) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{m2Seed, wallet.Bytes(), auctionHouse.Bytes(), tokenAccount.Bytes(), tokenMint.Bytes()}, M2ProgramAddress)
+}
+
+// FindM2ProgramAsSigner is the delegate of listed tokens
+func FindM2ProgramAsSigner() (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{m2Seed, m2SignerSeed}, M2ProgramAddress)
+}
+
+func FindM2AuctionHouseTreasury(auctionHouse solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress([][]byte{m2Seed, auctionHouse.Bytes(), m2TreasurySeed}, M2ProgramAddress)
+}
+
+func FindMetadata(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{metadataSeed, TokenMetadataProgramAddress.Bytes(), mint.Bytes()}, TokenMetadataProgramAddress)
+	return address, err
+}
+
+func FindMasterEdition(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{metadataSeed, TokenMetadataProgramAddress.Bytes(), mint.Bytes(), editionSeed}, TokenMetadataProgramAddress)
+	return address, err
+}
+
+// FindTokenRecord pNFT token record of the token account
+func FindTokenRecord(mint solana.PublicKey, tokenAccount solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{metadataSeed, TokenMetadataProgramAddress.Bytes(), mint.Bytes(), tokenRecordSeed, tokenAccount.Bytes()}, TokenMetadataProgramAddress)
+	return address, err
+}
+
+// M2ProgrammableNft marks the listing as pNFT, royalties are enforced by token metadata through the ruleset
+type M2ProgrammableNft struct {
+	// AuthorizationRules ruleset from metadata programmable config, zero when the pNFT has none
+	AuthorizationRules solana.PublicKey
+}
+
+type M2BuyNowParams struct {
+	Buyer   solana.PublicKey
+	Listing *M2SellerTradeState
+	// Creators from token metadata, they receive royalties and are passed as remaining accounts
+	Creators              []solana.PublicKey
+	BuyerCreatorRoyaltyBp uint16
+	MakerFeeBp            int16
+	TakerFeeBp            uint16
+	// BuyerReferral defaults to listing's seller referral
+	BuyerReferral solana.PublicKey
+	// Programmable is nil for legacy NFTs
+	Programmable *M2ProgrammableNft
+}
+
+// MakeM2BuyNowIxs buys a listing atomically, buy_v2 deposits the price into buyer's escrow and creates buyer trade state,
+// execute_sale_v2 (mip1_execute_sale_v2 for pNFTs) matches it with the seller trade state
+func MakeM2BuyNowIxs(params M2BuyNowParams) ([]solana.Instruction, error) {
+	if params.Listing == nil {
+		return nil, ErrNilListing
+	}
+	// escrow and buy_v2 only take SOL
+	if !params.Listing.PaymentMint.IsZero() {
+		return nil, ErrUnsupportedPaymentMint
+	}
+	if params.Listing.IsExpired(time.Now().Unix()) {
+		return nil, ErrListingExpired
+	}
+
+	buyIx, err := MakeM2BuyV2Ix(params.Buyer, params.Listing.AuctionHouseKey, params.Listing.TokenMint,
+		params.Listing.BuyerPrice, params.Listing.TokenSize, params.BuyerCreatorRoyaltyBp, params.buyerReferral())
+	if err != nil {
+		return nil, err
+	}
+
+	var executeIx solana.Instruction
+	if params.Programmable != nil {
+		executeIx, err = MakeM2Mip1ExecuteSaleV2Ix(params)
+	} else {
+		executeIx, err = MakeM2ExecuteSaleV2Ix(params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{buyIx, executeIx}, nil
+}
+
+// MakeM2BuyV2Ix builds buy_v2, buyer trade state doesn't expire
+func MakeM2BuyV2Ix(
+	buyer solana.PublicKey,
+	auctionHouse solana.PublicKey,
+	tokenMint solana.PublicKey,
+	buyerPrice uint64,
+	tokenSize uint64,
+	buyerCreatorRoyaltyBp uint16,
+	buyerReferral solana.PublicKey,
+) (solana.Instruction, error) {
+	metadata, err := FindMetadata(tokenMint)
+	if err != nil {
+		return nil, err
+	}
+	escrowPaymentAccount, _, err := FindM2EscrowPaymentAccount(auctionHouse, buyer)
+	if err != nil {
+		return nil, err
+	}
+	buyerTradeState, _, err := FindM2BuyerTradeState(buyer, auctionHouse, tokenMint)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(buyer, true, true),
+		solana.NewAccountMeta(M2Notary, false, false),
+		solana.NewAccountMeta(tokenMint, false, false),
+		solana.NewAccountMeta(metadata, false, false),
+		solana.NewAccountMeta(escrowPaymentAccount, true, false),
+		solana.NewAccountMeta(M2AuctionHouseAuthority, false, false),
+		solana.NewAccountMeta(auctionHouse, false, false),
+		solana.NewAccountMeta(buyerTradeState, true, false),
+		solana.NewAccountMeta(buyerReferral, false, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}
+
+	data := make([]byte, M2BuyV2InstructionSize)
+	copy(data, M2BuyV2Discriminator[:])
+	bin.LE.PutUint64(data[8:], buyerPrice)
+	bin.LE.PutUint64(data[16:], tokenSize)
+	bin.LE.PutUint64(data[24:], 0) // buyer state expiry
+	bin.LE.PutUint16(data[32:], buyerCreatorRoyaltyBp)
+	// extra args vec length stays zero
+
+	return solana.NewInstruction(M2ProgramAddress, accounts, data), nil
+}
+
+// m2SaleAccounts PDAs shared by execute_sale_v2 and mip1_execute_sale_v2
+type m2SaleAccounts struct {
+	metadata                  solana.PublicKey
+	programAsSigner           solana.PublicKey
+	programAsSignerBump       uint8
+	treasury                  solana.PublicKey
+	sellerTradeState          solana.PublicKey
+	buyerTradeState           solana.PublicKey
+	buyerEscrowPaymentAccount solana.PublicKey
+	escrowPaymentBump         uint8
+	buyerReceiptTokenAccount  solana.PublicKey
+}
+
+func findM2SaleAccounts(params M2BuyNowParams) (*m2SaleAccounts, error) {
+	listing := params.Listing
+	var accounts m2SaleAccounts
+	var err error
+	if accounts.metadata, err = FindMetadata(listing.TokenMint); err != nil {
+		return nil, err
+	}
+	if accounts.programAsSigner, accounts.programAsSignerBump, err = FindM2ProgramAsSigner(); err != nil {
+		return nil, err
+	}
+	if accounts.treasury, _, err = FindM2AuctionHouseTreasury(listing.AuctionHouseKey); err != nil {
+		return nil, err
+	}
+	if accounts.sellerTradeState, _, err = FindM2SellerTradeState(listing.Seller, listing.AuctionHouseKey, listing.TokenAccount, listing.TokenMint); err != nil {
+		return nil, err
+	}
+	if accounts.buyerTradeState, _, err = FindM2BuyerTradeState(params.Buyer, listing.AuctionHouseKey, listing.TokenMint); err != nil {
+		return nil, err
+	}
+	if accounts.buyerEscrowPaymentAccount, accounts.escrowPaymentBump, err = FindM2EscrowPaymentAccount(listing.AuctionHouseKey, params.Buyer); err != nil {
+		return nil, err
+	}
+	if accounts.buyerReceiptTokenAccount, _, err = solana.FindAssociatedTokenAddress(params.Buyer, listing.TokenMint); err != nil {
+		return nil, err
+	}
+	return &accounts, nil
+}
+
+func creatorAccounts(creators []solana.PublicKey) solana.AccountMetaSlice {
+	accounts := make(solana.AccountMetaSlice, 0, len(creators))
+	for _, creator := range creators {
+		accounts = append(accounts, solana.NewAccountMeta(creator, true, false))
+	}
+	return accounts
+}
+
+// MakeM2ExecuteSaleV2Ix builds execute_sale_v2 for legacy NFTs, buyer pays the transaction
+func MakeM2ExecuteSaleV2Ix(params M2BuyNowParams) (solana.Instruction, error) {
+	if params.Listing == nil {
+		return nil, ErrNilListing
+	}
+	listing := params.Listing
+	sale, err := findM2SaleAccounts(params)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(params.Buyer, true, true), // payer
+		solana.NewAccountMeta(params.Buyer, true, false),
+		solana.NewAccountMeta(listing.Seller, true, false),
+		solana.NewAccountMeta(M2Notary, false, false),
+		solana.NewAccountMeta(sale.programAsSigner, false, false),
+		solana.NewAccountMeta(listing.TokenAccount, true, false),
+		solana.NewAccountMeta(listing.TokenMint, false, false),
+		solana.NewAccountMeta(sale.metadata, false, false),
+		solana.NewAccountMeta(M2AuctionHouseAuthority, false, false),
+		solana.NewAccountMeta(listing.AuctionHouseKey, false, false),
+		solana.NewAccountMeta(sale.treasury, true, false),
+		solana.NewAccountMeta(sale.sellerTradeState, true, false),
+		solana.NewAccountMeta(sale.buyerTradeState, true, false),
+		solana.NewAccountMeta(sale.buyerEscrowPaymentAccount, true, false),
+		solana.NewAccountMeta(sale.buyerReceiptTokenAccount, true, false),
+		solana.NewAccountMeta(params.buyerReferral(), true, false),
+		solana.NewAccountMeta(listing.SellerReferral, true, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+		solana.NewAccountMeta(solana.SPLAssociatedTokenAccountProgramID, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+		solana.NewAccountMeta(solana.SysVarRentPubkey, false, false),
+	}
+	accounts = append(accounts, creatorAccounts(params.Creators)...)
+
+	data := make([]byte, M2ExecuteSaleV2InstructionSize)
+	copy(data, M2ExecuteSaleV2Discriminator[:])
+	data[8] = sale.escrowPaymentBump
+	data[9] = sale.programAsSignerBump
+	bin.LE.PutUint64(data[10:], listing.BuyerPrice)
+	bin.LE.PutUint64(data[18:], listing.TokenSize)
+	bin.LE.PutUint64(data[26:], 0) // buyer state expiry
+	bin.LE.PutUint64(data[34:], uint64(listing.Expiry))
+	bin.LE.PutUint16(data[42:], uint16(params.MakerFeeBp))
+	bin.LE.PutUint16(data[44:], params.TakerFeeBp)
+
+	return solana.NewInstruction(M2ProgramAddress, accounts, data), nil
+}
+
+// MakeM2Mip1ExecuteSaleV2Ix builds mip1_execute_sale_v2 for pNFTs, token is transferred by token metadata
+// so token records of both sides, edition and the ruleset are required
+func MakeM2Mip1ExecuteSaleV2Ix(params M2BuyNowParams) (solana.Instruction, error) {
+	if params.Listing == nil {
+		return nil, ErrNilListing
+	}
+	listing := params.Listing
+	sale, err := findM2SaleAccounts(params)
+	if err != nil {
+		return nil, err
+	}
+	edition, err := FindMasterEdition(listing.TokenMint)
+	if err != nil {
+		return nil, err
+	}
+	ownerTokenRecord, err := FindTokenRecord(listing.TokenMint, listing.TokenAccount)
+	if err != nil {
+		return nil, err
+	}
+	destinationTokenRecord, err := FindTokenRecord(listing.TokenMint, sale.buyerReceiptTokenAccount)
+	if err != nil {
+		return nil, err
+	}
+	// absent optional accounts are passed as the program id
+	authorizationRules := M2ProgramAddress
+	if params.Programmable != nil && !params.Programmable.AuthorizationRules.IsZero() {
+		authorizationRules = params.Programmable.AuthorizationRules
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(params.Buyer, true, true), // payer
+		solana.NewAccountMeta(params.Buyer, true, false),
+		solana.NewAccountMeta(listing.Seller, true, false),
+		solana.NewAccountMeta(M2Notary, false, false),
+		solana.NewAccountMeta(sale.programAsSigner, false, false),
+		solana.NewAccountMeta(listing.TokenAccount, true, false),
+		solana.NewAccountMeta(sale.buyerReceiptTokenAccount, true, false),
+		solana.NewAccountMeta(listing.TokenMint, false, false),
+		solana.NewAccountMeta(sale.metadata, true, false),
+		solana.NewAccountMeta(M2AuctionHouseAuthority, false, false),
+		solana.NewAccountMeta(listing.AuctionHouseKey, false, false),
+		solana.NewAccountMeta(sale.treasury, true, false),
+		solana.NewAccountMeta(sale.sellerTradeState, true, false),
+		solana.NewAccountMeta(sale.buyerTradeState, true, false),
+		solana.NewAccountMeta(sale.buyerEscrowPaymentAccount, true, false),
+		solana.NewAccountMeta(params.buyerReferral(), true, false),
+		solana.NewAccountMeta(listing.SellerReferral, true, false),
+		solana.NewAccountMeta(TokenMetadataProgramAddress, false, false),
+		solana.NewAccountMeta(edition, false, false),
+		solana.NewAccountMeta(AuthorizationRulesProgramAddress, false, false),
+		solana.NewAccountMeta(authorizationRules, false, false),
+		solana.NewAccountMeta(solana.SysVarInstructionsPubkey, false, false),
+		solana.NewAccountMeta(ownerTokenRecord, true, false),
+		solana.NewAccountMeta(destinationTokenRecord, true, false),
+		solana.NewAccountMeta(solana.SPLAssociatedTokenAccountProgramID, false, false),
+		solana.NewAccountMeta(solana.TokenProgramID, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}
+	accounts = append(accounts, creatorAccounts(params.Creators)...)
+
+	data := make([]byte, M2Mip1ExecuteSaleV2InstructionSize)
+	copy(data, M2Mip1ExecuteSaleV2Discriminator[:])
+	bin.LE.PutUint64(data[8:], listing.BuyerPrice)
+	bin.LE.PutUint16(data[16:], uint16(params.MakerFeeBp))
+	bin.LE.PutUint16(data[18:], params.TakerFeeBp)
+
+	return solana.NewInstruction(M2ProgramAddress, accounts, data), nil
+}
+
+func (p M2BuyNowParams) buyerReferral() solana.PublicKey {
+	if p.BuyerReferral.IsZero() && p.Listing != nil {
+		return p.Listing.SellerReferral
+	}
+	return p.BuyerReferral
+}
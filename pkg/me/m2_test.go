@@ -0,0 +1,140 @@
+package me
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func newTestListing() *M2SellerTradeState {
+	return &M2SellerTradeState{
+		AuctionHouseKey: M2AuctionHouse,
+		Seller:          solana.NewWallet().PublicKey(),
+		SellerReferral:  solana.NewWallet().PublicKey(),
+		BuyerPrice:      1_500_000_000,
+		TokenMint:       solana.NewWallet().PublicKey(),
+		TokenAccount:    solana.NewWallet().PublicKey(),
+		TokenSize:       1,
+		Expiry:          -1,
+	}
+}
+
+func TestMakeM2BuyNowIxs(t *testing.T) {
+	buyer := solana.NewWallet().PublicKey()
+	creator := solana.NewWallet().PublicKey()
+	listing := newTestListing()
+
+	ixs, err := MakeM2BuyNowIxs(M2BuyNowParams{
+		Buyer:      buyer,
+		Listing:    listing,
+		Creators:   []solana.PublicKey{creator},
+		TakerFeeBp: M2TakerFeeBp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ixs) != 2 {
+		t.Fatalf("got %d instructions", len(ixs))
+	}
+
+	buyData, err := ixs[0].Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buyData) != M2BuyV2InstructionSize || [8]byte(buyData[:8]) != M2BuyV2Discriminator {
+		t.Fatal("unexpected buy_v2 data")
+	}
+	if binary.LittleEndian.Uint64(buyData[8:16]) != listing.BuyerPrice || binary.LittleEndian.Uint64(buyData[16:24]) != 1 {
+		t.Fatal("unexpected buy_v2 price or size")
+	}
+	buyerTradeState, _, _ := FindM2BuyerTradeState(buyer, M2AuctionHouse, listing.TokenMint)
+	if buyAccounts := ixs[0].Accounts(); buyAccounts[7].PublicKey != buyerTradeState || buyAccounts[8].PublicKey != listing.SellerReferral {
+		t.Fatal("unexpected buy_v2 accounts")
+	}
+
+	saleData, err := ixs[1].Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saleData) != M2ExecuteSaleV2InstructionSize || [8]byte(saleData[:8]) != M2ExecuteSaleV2Discriminator {
+		t.Fatal("unexpected execute_sale_v2 data")
+	}
+	_, escrowBump, _ := FindM2EscrowPaymentAccount(M2AuctionHouse, buyer)
+	_, signerBump, _ := FindM2ProgramAsSigner()
+	if saleData[8] != escrowBump || saleData[9] != signerBump {
+		t.Fatal("unexpected bumps")
+	}
+	if int64(binary.LittleEndian.Uint64(saleData[34:42])) != listing.Expiry || binary.LittleEndian.Uint16(saleData[44:46]) != M2TakerFeeBp {
+		t.Fatal("unexpected execute_sale_v2 args")
+	}
+	saleAccounts := ixs[1].Accounts()
+	sellerTradeState, _, _ := FindM2SellerTradeState(listing.Seller, M2AuctionHouse, listing.TokenAccount, listing.TokenMint)
+	if len(saleAccounts) != 22 || saleAccounts[11].PublicKey != sellerTradeState || saleAccounts[21].PublicKey != creator {
+		t.Fatal("unexpected execute_sale_v2 accounts")
+	}
+}
+
+func TestMakeM2BuyNowIxsProgrammable(t *testing.T) {
+	buyer := solana.NewWallet().PublicKey()
+	ruleset := solana.NewWallet().PublicKey()
+	listing := newTestListing()
+
+	ixs, err := MakeM2BuyNowIxs(M2BuyNowParams{
+		Buyer:        buyer,
+		Listing:      listing,
+		MakerFeeBp:   -50,
+		Programmable: &M2ProgrammableNft{AuthorizationRules: ruleset},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ixs[1].Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != M2Mip1ExecuteSaleV2InstructionSize || [8]byte(data[:8]) != M2Mip1ExecuteSaleV2Discriminator {
+		t.Fatal("unexpected mip1_execute_sale_v2 data")
+	}
+	if int16(binary.LittleEndian.Uint16(data[16:18])) != -50 {
+		t.Fatal("unexpected maker fee")
+	}
+
+	accounts := ixs[1].Accounts()
+	receipt, _, _ := solana.FindAssociatedTokenAddress(buyer, listing.TokenMint)
+	ownerRecord, _ := FindTokenRecord(listing.TokenMint, listing.TokenAccount)
+	destinationRecord, _ := FindTokenRecord(listing.TokenMint, receipt)
+	if accounts[20].PublicKey != ruleset || accounts[22].PublicKey != ownerRecord || accounts[23].PublicKey != destinationRecord {
+		t.Fatal("unexpected pNFT accounts")
+	}
+
+	// pNFT without ruleset passes the program id
+	ix, err := MakeM2Mip1ExecuteSaleV2Ix(M2BuyNowParams{Buyer: buyer, Listing: listing, Programmable: &M2ProgrammableNft{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ix.Accounts()[20].PublicKey != M2ProgramAddress {
+		t.Fatal("expected program id for missing ruleset")
+	}
+}
+
+func TestMakeM2BuyNowIxsNilListing(t *testing.T) {
+	if _, err := MakeM2BuyNowIxs(M2BuyNowParams{}); err != ErrNilListing {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestMakeM2BuyNowIxsRejectsListing(t *testing.T) {
+	splListing := newTestListing()
+	splListing.PaymentMint = solana.NewWallet().PublicKey()
+	if _, err := MakeM2BuyNowIxs(M2BuyNowParams{Buyer: solana.NewWallet().PublicKey(), Listing: splListing}); err != ErrUnsupportedPaymentMint {
+		t.Fatalf("got %v for spl listing", err)
+	}
+
+	expiredListing := newTestListing()
+	expiredListing.Expiry = 1_700_000_000
+	if _, err := MakeM2BuyNowIxs(M2BuyNowParams{Buyer: solana.NewWallet().PublicKey(), Listing: expiredListing}); err != ErrListingExpired {
+		t.Fatalf("got %v for expired listing", err)
+	}
+}
@@ -0,0 +1,278 @@
+package me
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/bits"
+
+	"jito-bot/pkg/helius"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/valyala/fastjson"
+)
+
+var (
+	BubblegumProgramAddress          = solana.MustPublicKeyFromBase58("BGUMAp9Gq7iTEuizy4pqaxsTyUCBK68MDfK752saRPUY")
+	AccountCompressionProgramAddress = solana.MustPublicKeyFromBase58("cmtDvXumGCrqC1Age74AVPhSRVXJMd8PJS7Yb7Xs2ND")
+	NoopProgramAddress               = solana.MustPublicKeyFromBase58("noopb9bkMVfRPU8AsbpTUg8AQkHtKwMYZiFUjNRtMmV")
+)
+
+var M3BuyDiscriminator = [...]byte{
+	0x66, 0x06, 0x3d, 0x12, 0x01, 0xda, 0xeb, 0xea,
+}
+
+var (
+	ErrInvalidMerkleTree = errors.New("me: invalid merkle tree account")
+	ErrInvalidAssetProof = errors.New("me: invalid asset proof")
+	ErrNotCompressed     = errors.New("me: asset is not compressed")
+)
+
+const (
+	// merkleTreeHeaderSize account type + header version + max buffer size + max depth + authority + creation slot + padding
+	merkleTreeHeaderSize  = 1 + 1 + 4 + 4 + 32 + 8 + 6
+	merkleTreeAccountType = 1
+)
+
+// FindTreeAuthority bubblegum tree config of the merkle tree
+func FindTreeAuthority(merkleTree solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{merkleTree.Bytes()}, BubblegumProgramAddress)
+	return address, err
+}
+
+// MerkleTreeCanopyDepth reads spl account compression tree, the top canopy depth levels are stored on-chain
+// and can be left out of the proof
+func MerkleTreeCanopyDepth(data []byte) (int, error) {
+	if len(data) < merkleTreeHeaderSize || data[0] != merkleTreeAccountType {
+		return 0, ErrInvalidMerkleTree
+	}
+	maxBufferSize := int(bin.LE.Uint32(data[2:6]))
+	maxDepth := int(bin.LE.Uint32(data[6:10]))
+
+	// sequence number + active index + buffer size, change logs and rightmost path
+	changeLogSize := 32 + 32*maxDepth + 4 + 4
+	rightmostPathSize := 32*maxDepth + 32 + 4 + 4
+	treeSize := 8 + 8 + 8 + maxBufferSize*changeLogSize + rightmostPathSize
+
+	canopySize := len(data) - merkleTreeHeaderSize - treeSize
+	if canopySize < 0 || canopySize%32 != 0 {
+		return 0, ErrInvalidMerkleTree
+	}
+	// canopy keeps 2^1 + ... + 2^depth nodes
+	return bits.Len(uint(canopySize/32+2)) - 2, nil
+}
+
+type M3Creator struct {
+	Address  solana.PublicKey
+	Share    uint8
+	Verified bool
+}
+
+// CompressedAsset leaf of cNFT from DAS getAsset and getAssetProof
+type CompressedAsset struct {
+	DataHash             [32]byte
+	CreatorHash          [32]byte
+	Nonce                uint64
+	Creators             []M3Creator
+	SellerFeeBasisPoints uint16
+	Root                 [32]byte
+	// Proof from leaf to root, canopy is not cut off
+	Proof []solana.PublicKey
+}
+
+// LoadCompressedAsset fetches leaf hashes and proof of the asset, the client isn't safe for concurrent use
+func LoadCompressedAsset(das *helius.HeliusClient, assetId solana.PublicKey) (*CompressedAsset, error) {
+	asset, err := das.GetAsset(assetId.String())
+	if err != nil {
+		return nil, err
+	}
+	var compressed CompressedAsset
+	if err := parseDasAsset(asset.Get("result"), &compressed); err != nil {
+		return nil, err
+	}
+
+	proof, err := das.GetAssetProof(assetId.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := parseDasAssetProof(proof.Get("result"), &compressed); err != nil {
+		return nil, err
+	}
+	return &compressed, nil
+}
+
+func parseDasAsset(result *fastjson.Value, asset *CompressedAsset) error {
+	if result == nil || !result.GetBool("compression", "compressed") {
+		return ErrNotCompressed
+	}
+	var err error
+	if asset.DataHash, err = decodeHash(result.GetStringBytes("compression", "data_hash")); err != nil {
+		return err
+	}
+	if asset.CreatorHash, err = decodeHash(result.GetStringBytes("compression", "creator_hash")); err != nil {
+		return err
+	}
+	asset.Nonce = result.GetUint64("compression", "leaf_id")
+	asset.SellerFeeBasisPoints = uint16(result.GetUint("royalty", "basis_points"))
+
+	creators := result.GetArray("creators")
+	asset.Creators = make([]M3Creator, 0, len(creators))
+	for _, creator := range creators {
+		address, err := solana.PublicKeyFromBase58(string(creator.GetStringBytes("address")))
+		if err != nil {
+			return err
+		}
+		asset.Creators = append(asset.Creators, M3Creator{
+			Address:  address,
+			Share:    uint8(creator.GetUint("share")),
+			Verified: creator.GetBool("verified"),
+		})
+	}
+	return nil
+}
+
+func parseDasAssetProof(result *fastjson.Value, asset *CompressedAsset) error {
+	if result == nil {
+		return ErrInvalidAssetProof
+	}
+	var err error
+	if asset.Root, err = decodeHash(result.GetStringBytes("root")); err != nil {
+		return err
+	}
+	nodes := result.GetArray("proof")
+	asset.Proof = make([]solana.PublicKey, 0, len(nodes))
+	for _, node := range nodes {
+		key, err := solana.PublicKeyFromBase58(string(node.GetStringBytes()))
+		if err != nil {
+			return err
+		}
+		asset.Proof = append(asset.Proof, key)
+	}
+	return nil
+}
+
+func decodeHash(data []byte) ([32]byte, error) {
+	key, err := solana.PublicKeyFromBase58(string(data))
+	if err != nil {
+		return [32]byte{}, ErrInvalidAssetProof
+	}
+	return key, nil
+}
+
+type m3BuyArgs struct {
+	BuyerPrice            uint64
+	DataHash              [32]byte
+	CreatorHash           [32]byte
+	Nonce                 uint64
+	Root                  [32]byte
+	Index                 uint32
+	CreatorShares         []uint16
+	CreatorVerified       []bool
+	SellerFeeBasisPoints  uint16
+	MakerFeeBp            int16
+	TakerFeeBp            uint16
+	BuyerCreatorRoyaltyBp uint16
+}
+
+type M3BuyParams struct {
+	Buyer   solana.PublicKey
+	Listing *M3SellerTradeState
+	Asset   *CompressedAsset
+	// CanopyDepth proof levels stored in the tree account, see MerkleTreeCanopyDepth
+	CanopyDepth           int
+	BuyerCreatorRoyaltyBp uint16
+	MakerFeeBp            int16
+	TakerFeeBp            uint16
+	// BuyerReferral defaults to listing's seller referral
+	BuyerReferral solana.PublicKey
+}
+
+// MakeM3BuyIx buys cNFT listing, creators and then the proof without canopy nodes are the remaining accounts.
+// Listing address must be set, FindAllM3SellterTradeStates fills it
+func MakeM3BuyIx(params M3BuyParams) (solana.Instruction, error) {
+	if params.Listing == nil {
+		return nil, ErrNilListing
+	}
+	if params.Asset == nil || params.CanopyDepth < 0 || params.CanopyDepth > len(params.Asset.Proof) {
+		return nil, ErrInvalidAssetProof
+	}
+	listing, asset := params.Listing, params.Asset
+	treeAuthority, err := FindTreeAuthority(listing.MerkleTree)
+	if err != nil {
+		return nil, err
+	}
+	buyerReferral := params.BuyerReferral
+	if buyerReferral.IsZero() {
+		buyerReferral = listing.SellerReferral
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(params.Buyer, true, true), // payer
+		solana.NewAccountMeta(params.Buyer, false, false),
+		solana.NewAccountMeta(listing.Seller, true, false),
+		solana.NewAccountMeta(M2Notary, false, false),
+		solana.NewAccountMeta(treeAuthority, false, false),
+		solana.NewAccountMeta(listing.Address, true, false),
+		solana.NewAccountMeta(buyerReferral, true, false),
+		solana.NewAccountMeta(listing.SellerReferral, true, false),
+		solana.NewAccountMeta(listing.MerkleTree, true, false),
+		solana.NewAccountMeta(NoopProgramAddress, false, false),
+		solana.NewAccountMeta(BubblegumProgramAddress, false, false),
+		solana.NewAccountMeta(AccountCompressionProgramAddress, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+	}
+
+	args := m3BuyArgs{
+		BuyerPrice:            listing.BuyerPrice,
+		DataHash:              asset.DataHash,
+		CreatorHash:           asset.CreatorHash,
+		Nonce:                 asset.Nonce,
+		Root:                  asset.Root,
+		Index:                 listing.Index,
+		CreatorShares:         make([]uint16, 0, len(asset.Creators)),
+		CreatorVerified:       make([]bool, 0, len(asset.Creators)),
+		SellerFeeBasisPoints:  asset.SellerFeeBasisPoints,
+		MakerFeeBp:            params.MakerFeeBp,
+		TakerFeeBp:            params.TakerFeeBp,
+		BuyerCreatorRoyaltyBp: params.BuyerCreatorRoyaltyBp,
+	}
+	for _, creator := range asset.Creators {
+		accounts = append(accounts, solana.NewAccountMeta(creator.Address, true, false))
+		args.CreatorShares = append(args.CreatorShares, uint16(creator.Share))
+		args.CreatorVerified = append(args.CreatorVerified, creator.Verified)
+	}
+	for _, node := range asset.Proof[:len(asset.Proof)-params.CanopyDepth] {
+		accounts = append(accounts, solana.NewAccountMeta(node, false, false))
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(M3BuyDiscriminator[:])
+	if err := bin.NewBorshEncoder(buf).Encode(args); err != nil {
+		return nil, err
+	}
+	return solana.NewInstruction(M3ProgramAddress, accounts, buf.Bytes()), nil
+}
+
+// LoadM3BuyIx fetches asset proof and canopy depth of the listing's tree and builds the buy
+func LoadM3BuyIx(connection *rpc.Client, das *helius.HeliusClient, params M3BuyParams) (solana.Instruction, error) {
+	if params.Listing == nil {
+		return nil, ErrNilListing
+	}
+	asset, err := LoadCompressedAsset(das, params.Listing.AssetId)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := connection.GetAccountInfo(context.Background(), params.Listing.MerkleTree)
+	if err != nil {
+		return nil, err
+	}
+	canopyDepth, err := MerkleTreeCanopyDepth(tree.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	params.Asset = asset
+	params.CanopyDepth = canopyDepth
+	return MakeM3BuyIx(params)
+}
@@ -0,0 +1,136 @@
+package me
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/valyala/fastjson"
+)
+
+func newTestMerkleTree(maxDepth, maxBufferSize, canopyDepth int) []byte {
+	treeSize := 8 + 8 + 8 + maxBufferSize*(32+32*maxDepth+8) + 32*maxDepth + 32 + 8
+	canopySize := 32 * ((1 << (canopyDepth + 1)) - 2)
+	data := make([]byte, merkleTreeHeaderSize+treeSize+canopySize)
+	data[0] = merkleTreeAccountType
+	binary.LittleEndian.PutUint32(data[2:6], uint32(maxBufferSize))
+	binary.LittleEndian.PutUint32(data[6:10], uint32(maxDepth))
+	return data
+}
+
+func TestMerkleTreeCanopyDepth(t *testing.T) {
+	for _, canopyDepth := range []int{0, 1, 10, 14} {
+		depth, err := MerkleTreeCanopyDepth(newTestMerkleTree(20, 64, canopyDepth))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if depth != canopyDepth {
+			t.Fatalf("got canopy depth %d, expected %d", depth, canopyDepth)
+		}
+	}
+
+	if _, err := MerkleTreeCanopyDepth(newTestMerkleTree(14, 64, 0)[:100]); err != ErrInvalidMerkleTree {
+		t.Fatalf("got %v for truncated tree", err)
+	}
+}
+
+func TestParseDasAsset(t *testing.T) {
+	dataHash, creatorHash, root := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	creator, node := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	asset := fastjson.MustParse(`{
+		"compression": {"compressed": true, "data_hash": "` + dataHash.String() + `", "creator_hash": "` + creatorHash.String() + `", "leaf_id": 4321},
+		"royalty": {"basis_points": 500},
+		"creators": [{"address": "` + creator.String() + `", "share": 100, "verified": true}]
+	}`)
+	proof := fastjson.MustParse(`{"root": "` + root.String() + `", "proof": ["` + node.String() + `"]}`)
+
+	var compressed CompressedAsset
+	if err := parseDasAsset(asset, &compressed); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseDasAssetProof(proof, &compressed); err != nil {
+		t.Fatal(err)
+	}
+	if compressed.DataHash != dataHash || compressed.CreatorHash != creatorHash || compressed.Root != root {
+		t.Fatal("unexpected hashes")
+	}
+	if compressed.Nonce != 4321 || compressed.SellerFeeBasisPoints != 500 {
+		t.Fatalf("unexpected leaf %+v", compressed)
+	}
+	if len(compressed.Creators) != 1 || compressed.Creators[0] != (M3Creator{creator, 100, true}) {
+		t.Fatalf("unexpected creators %+v", compressed.Creators)
+	}
+	if len(compressed.Proof) != 1 || compressed.Proof[0] != node {
+		t.Fatal("unexpected proof")
+	}
+
+	if err := parseDasAsset(fastjson.MustParse(`{"compression": {"compressed": false}}`), &compressed); err != ErrNotCompressed {
+		t.Fatalf("got %v for uncompressed asset", err)
+	}
+}
+
+func TestMakeM3BuyIx(t *testing.T) {
+	listing := &M3SellerTradeState{
+		Address:        solana.NewWallet().PublicKey(),
+		Seller:         solana.NewWallet().PublicKey(),
+		SellerReferral: solana.NewWallet().PublicKey(),
+		BuyerPrice:     250_000_000,
+		AssetId:        solana.NewWallet().PublicKey(),
+		MerkleTree:     solana.NewWallet().PublicKey(),
+		Index:          77,
+	}
+	asset := &CompressedAsset{
+		DataHash:             [32]byte{1},
+		CreatorHash:          [32]byte{2},
+		Nonce:                77,
+		Root:                 [32]byte{3},
+		SellerFeeBasisPoints: 500,
+		Creators: []M3Creator{
+			{Address: solana.NewWallet().PublicKey(), Share: 60, Verified: true},
+			{Address: solana.NewWallet().PublicKey(), Share: 40},
+		},
+	}
+	for i := 0; i < 14; i++ {
+		asset.Proof = append(asset.Proof, solana.NewWallet().PublicKey())
+	}
+
+	ix, err := MakeM3BuyIx(M3BuyParams{
+		Buyer:       solana.NewWallet().PublicKey(),
+		Listing:     listing,
+		Asset:       asset,
+		CanopyDepth: 10,
+		TakerFeeBp:  M2TakerFeeBp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accounts := ix.Accounts()
+	// 13 fixed accounts, 2 creators and 4 proof nodes below the canopy
+	if len(accounts) != 13+2+4 {
+		t.Fatalf("got %d accounts", len(accounts))
+	}
+	if accounts[5].PublicKey != listing.Address || accounts[13].PublicKey != asset.Creators[0].Address || accounts[18].PublicKey != asset.Proof[3] {
+		t.Fatal("unexpected accounts")
+	}
+
+	data, err := ix.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if [8]byte(data[:8]) != M3BuyDiscriminator || binary.LittleEndian.Uint64(data[8:16]) != listing.BuyerPrice {
+		t.Fatal("unexpected header")
+	}
+	// price + data hash + creator hash + nonce + root + index + shares (4 + 2*2) + verified (4 + 2) + 4 fee fields
+	if len(data) != 8+8+32+32+8+32+4+8+6+8 {
+		t.Fatalf("got data length %d", len(data))
+	}
+	if binary.LittleEndian.Uint32(data[120:124]) != 77 || binary.LittleEndian.Uint16(data[128:130]) != 60 {
+		t.Fatal("unexpected index or creator shares")
+	}
+
+	if _, err := MakeM3BuyIx(M3BuyParams{Listing: listing, Asset: asset, CanopyDepth: 15}); err != ErrInvalidAssetProof {
+		t.Fatalf("got %v for canopy deeper than proof", err)
+	}
+}
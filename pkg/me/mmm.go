@@ -0,0 +1,215 @@
+package me
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var MMMProgramAddress = solana.MustPublicKeyFromBase58("mmm3XBJg5gk8XJxEKBvdgptZz6SgK4tXvn36sodowMc")
+
+var MMMPoolDiscriminator = [...]byte{
+	0xf1, 0x9a, 0x6d, 0x04, 0x11, 0xb1, 0x6d, 0xbc,
+}
+
+const (
+	MMMCurveLinear      uint8 = 0
+	MMMCurveExponential uint8 = 1
+)
+
+const (
+	MMMAllowlistEmpty uint8 = iota
+	MMMAllowlistFvc
+	MMMAllowlistMint
+	MMMAllowlistMcc
+	MMMAllowlistMetadata
+	MMMAllowlistGroup
+)
+
+const (
+	MMMAllowlistMaxLen = 6
+	// MMMPoolSize discriminator + curve and fees + referral + annotation + amounts + 4 keys + allowlists + buyside + shared escrow
+	MMMPoolSize   = 8 + 8 + 1 + 8 + 1 + 1 + 8 + 2 + 32 + 2 + 2 + 32 + 8 + 8 + 32*4 + 33*MMMAllowlistMaxLen + 8 + 32 + 8
+	bpDenominator = 10_000
+)
+
+var (
+	ErrUnknownCurve         = errors.New("me: unknown mmm curve type")
+	ErrPriceOutOfRange      = errors.New("me: mmm price out of range")
+	ErrInsufficientBuyside  = errors.New("me: mmm pool has not enough buyside payment")
+	ErrInsufficientSellside = errors.New("me: mmm pool has not enough assets")
+)
+
+type MMMAllowlist struct {
+	Kind  uint8
+	Value solana.PublicKey
+}
+
+type MMMPool struct {
+	Address solana.PublicKey `bin:"-"` // not part of the account data
+
+	SpotPrice               uint64
+	CurveType               uint8
+	CurveDelta              uint64 // lamports for linear, basis points for exponential curve
+	ReinvestFulfillBuy      bool
+	ReinvestFulfillSell     bool
+	Expiry                  int64 // 0 never expires
+	LpFeeBp                 uint16
+	Referral                solana.PublicKey
+	ReferralBp              uint16 // deprecated
+	BuysideCreatorRoyaltyBp uint16
+	CosignerAnnotation      [32]byte
+	SellsideAssetAmount     uint64
+	LpFeeEarned             uint64
+	Owner                   solana.PublicKey
+	Cosigner                solana.PublicKey
+	UUID                    solana.PublicKey
+	PaymentMint             solana.PublicKey
+	Allowlists              [MMMAllowlistMaxLen]MMMAllowlist
+	BuysidePaymentAmount    uint64
+	SharedEscrowAccount     solana.PublicKey
+	SharedEscrowCount       uint64
+}
+
+func ParseMMMPool(address solana.PublicKey, data []byte) (*MMMPool, error) {
+	if err := checkAccount(data, MMMPoolDiscriminator, MMMPoolSize); err != nil {
+		return nil, err
+	}
+	pool := &MMMPool{Address: address}
+	if err := bin.NewBorshDecoder(data[8:]).Decode(pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// FindAllMMMPools malformed accounts are skipped and counted
+func FindAllMMMPools(connection *rpc.Client) ([]*MMMPool, DecodeStats, error) {
+	var stats DecodeStats
+	gpa, err := findAllByDiscriminator(connection, MMMProgramAddress, MMMPoolDiscriminator)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	pools := make([]*MMMPool, 0, len(gpa))
+	for _, acc := range gpa {
+		pool, err := ParseMMMPool(acc.Pubkey, acc.Account.Data.GetBinary())
+		stats.add(err)
+		if err != nil {
+			continue
+		}
+		pools = append(pools, pool)
+	}
+
+	return pools, stats, nil
+}
+
+func (p *MMMPool) IsExpired(now int64) bool {
+	return p.Expiry != 0 && p.Expiry < now
+}
+
+// Collection is the certified collection the pool trades, nil when it's allowlisted otherwise
+func (p *MMMPool) Collection() *solana.PublicKey {
+	for _, allowlist := range p.Allowlists {
+		if allowlist.Kind == MMMAllowlistMcc {
+			return &allowlist.Value
+		}
+	}
+	return nil
+}
+
+// BuyPrice is the total the pool pays for n assets sold into it (fulfill_buy), before fees and royalties.
+// The first asset gets the spot price, every next one a price lower by delta
+func (p *MMMPool) BuyPrice(n uint64) (uint64, error) {
+	var total uint64
+	switch p.CurveType {
+	case MMMCurveLinear:
+		if n == 0 {
+			return 0, nil
+		}
+		// n * (2 * spot - (n - 1) * delta) / 2, the last price can't go below zero
+		decrease := new(big.Int).Mul(new(big.Int).SetUint64(n-1), new(big.Int).SetUint64(p.CurveDelta))
+		spot := new(big.Int).SetUint64(p.SpotPrice)
+		if decrease.Cmp(spot) > 0 {
+			return 0, ErrPriceOutOfRange
+		}
+		sum := new(big.Int).Sub(spot.Lsh(spot, 1), decrease)
+		sum.Mul(sum, new(big.Int).SetUint64(n)).Rsh(sum, 1)
+		if !sum.IsUint64() {
+			return 0, ErrPriceOutOfRange
+		}
+		total = sum.Uint64()
+	case MMMCurveExponential:
+		price := p.SpotPrice
+		for i := uint64(0); i < n; i++ {
+			var carry uint64
+			if total, carry = bits.Add64(total, price, 0); carry != 0 {
+				return 0, ErrPriceOutOfRange
+			}
+			price, _ = mulDiv(price, bpDenominator, bpDenominator+p.CurveDelta)
+		}
+	default:
+		return 0, ErrUnknownCurve
+	}
+
+	if total > p.BuysidePaymentAmount {
+		return 0, ErrInsufficientBuyside
+	}
+	return total, nil
+}
+
+// SellPrice is the total the pool asks for n assets bought from it (fulfill_sell), before fees and royalties.
+// Asks start one step above the spot price
+func (p *MMMPool) SellPrice(n uint64) (uint64, error) {
+	if n > p.SellsideAssetAmount {
+		return 0, ErrInsufficientSellside
+	}
+
+	var total uint64
+	switch p.CurveType {
+	case MMMCurveLinear:
+		// n * (2 * spot + (n + 1) * delta) / 2
+		sum := new(big.Int).Mul(new(big.Int).SetUint64(n), new(big.Int).SetUint64(p.CurveDelta))
+		sum.Add(sum, new(big.Int).SetUint64(p.CurveDelta))
+		sum.Add(sum, new(big.Int).Lsh(new(big.Int).SetUint64(p.SpotPrice), 1))
+		sum.Mul(sum, new(big.Int).SetUint64(n)).Rsh(sum, 1)
+		if !sum.IsUint64() {
+			return 0, ErrPriceOutOfRange
+		}
+		total = sum.Uint64()
+	case MMMCurveExponential:
+		price := p.SpotPrice
+		for i := uint64(0); i < n; i++ {
+			var ok bool
+			var carry uint64
+			if price, ok = mulDiv(price, bpDenominator+p.CurveDelta, bpDenominator); !ok {
+				return 0, ErrPriceOutOfRange
+			}
+			if total, carry = bits.Add64(total, price, 0); carry != 0 {
+				return 0, ErrPriceOutOfRange
+			}
+		}
+	default:
+		return 0, ErrUnknownCurve
+	}
+	return total, nil
+}
+
+// LpFee paid to the pool owner on top of the price
+func (p *MMMPool) LpFee(price uint64) uint64 {
+	fee, _ := mulDiv(price, uint64(p.LpFeeBp), bpDenominator)
+	return fee
+}
+
+// mulDiv is x * y / d rounded down with 128-bit intermediate, false when the result doesn't fit
+func mulDiv(x, y, d uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(x, y)
+	if hi >= d {
+		return 0, false
+	}
+	quo, _ := bits.Div64(hi, lo, d)
+	return quo, true
+}
@@ -0,0 +1,121 @@
+package me
+
+import (
+	"bytes"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestParseMMMPool(t *testing.T) {
+	collection := solana.NewWallet().PublicKey()
+	expected := MMMPool{
+		SpotPrice:            1_000_000_000,
+		CurveType:            MMMCurveExponential,
+		CurveDelta:           500,
+		Expiry:               1_700_000_000,
+		LpFeeBp:              150,
+		SellsideAssetAmount:  3,
+		Owner:                solana.NewWallet().PublicKey(),
+		BuysidePaymentAmount: 5_000_000_000,
+	}
+	expected.Allowlists[1] = MMMAllowlist{Kind: MMMAllowlistMcc, Value: collection}
+
+	buf := new(bytes.Buffer)
+	buf.Write(MMMPoolDiscriminator[:])
+	if err := bin.NewBorshEncoder(buf).Encode(expected); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != MMMPoolSize {
+		t.Fatalf("encoded %d bytes, expected %d", buf.Len(), MMMPoolSize)
+	}
+	address := solana.NewWallet().PublicKey()
+	pool, err := ParseMMMPool(address, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected.Address = address
+	if *pool != expected {
+		t.Fatalf("got %+v, expected %+v", pool, expected)
+	}
+	if c := pool.Collection(); c == nil || *c != collection {
+		t.Fatal("unexpected collection")
+	}
+	if !pool.IsExpired(1_700_000_001) || pool.IsExpired(1_600_000_000) {
+		t.Fatal("unexpected expiry")
+	}
+
+	if _, err := ParseMMMPool(address, buf.Bytes()[:100]); err != ErrInvalidAccountSize {
+		t.Fatalf("got %v for truncated pool", err)
+	}
+	if _, err := ParseMMMPool(address, M2SellerTradeStateV2Discriminator[:]); err != ErrInvalidDiscriminator {
+		t.Fatalf("got %v for wrong discriminator", err)
+	}
+}
+
+func TestMMMLinearPrices(t *testing.T) {
+	pool := &MMMPool{
+		SpotPrice:            1_000,
+		CurveType:            MMMCurveLinear,
+		CurveDelta:           100,
+		SellsideAssetAmount:  3,
+		BuysidePaymentAmount: 10_000,
+	}
+
+	// bids 1000, 900, 800
+	for n, expected := range []uint64{0, 1_000, 1_900, 2_700} {
+		if price, err := pool.BuyPrice(uint64(n)); err != nil || price != expected {
+			t.Fatalf("buy %d: got %d %v, expected %d", n, price, err, expected)
+		}
+	}
+	// asks 1100, 1200, 1300
+	for n, expected := range []uint64{0, 1_100, 2_300, 3_600} {
+		if price, err := pool.SellPrice(uint64(n)); err != nil || price != expected {
+			t.Fatalf("sell %d: got %d %v, expected %d", n, price, err, expected)
+		}
+	}
+
+	if _, err := pool.BuyPrice(12); err != ErrPriceOutOfRange {
+		t.Fatalf("got %v for bids below zero", err)
+	}
+	if _, err := pool.SellPrice(4); err != ErrInsufficientSellside {
+		t.Fatalf("got %v for missing assets", err)
+	}
+	pool.BuysidePaymentAmount = 1_899
+	if _, err := pool.BuyPrice(2); err != ErrInsufficientBuyside {
+		t.Fatalf("got %v for missing buyside payment", err)
+	}
+}
+
+func TestMMMExponentialPrices(t *testing.T) {
+	pool := &MMMPool{
+		SpotPrice:            1_000_000,
+		CurveType:            MMMCurveExponential,
+		CurveDelta:           1_000, // 10%
+		LpFeeBp:              250,
+		SellsideAssetAmount:  2,
+		BuysidePaymentAmount: 10_000_000,
+	}
+
+	// bids 1000000, 909090, 826445
+	if price, err := pool.BuyPrice(3); err != nil || price != 1_000_000+909_090+826_445 {
+		t.Fatalf("got %d %v", price, err)
+	}
+	// asks 1100000, 1210000
+	if price, err := pool.SellPrice(2); err != nil || price != 1_100_000+1_210_000 {
+		t.Fatalf("got %d %v", price, err)
+	}
+	if fee := pool.LpFee(1_100_000); fee != 27_500 {
+		t.Fatalf("got lp fee %d", fee)
+	}
+
+	pool.SpotPrice = ^uint64(0)
+	if _, err := pool.SellPrice(1); err != ErrPriceOutOfRange {
+		t.Fatalf("got %v for overflowing ask", err)
+	}
+	pool.CurveType = 7
+	if _, err := pool.SellPrice(1); err != ErrUnknownCurve {
+		t.Fatalf("got %v for unknown curve", err)
+	}
+}
@@ -2,7 +2,10 @@ package pyth
 
 import (
 	"encoding/binary"
+	"errors"
 	"math"
+
+	"github.com/gagliardetto/solana-go"
 )
 
 const PriceConfIntervals float64 = 2.12
@@ -11,6 +14,30 @@ const Magic uint32 = 0xa1b2c3d4
 const Version uint32 = 2
 const MaxSlotDifference = 25
 
+// MaxPriceComponents PC_NUM_COMP, max number of publishers of a price account
+const MaxPriceComponents = 32
+
+// MaxMappingProducts PC_MAP_TABLE_SIZE
+const MaxMappingProducts = 640
+
+const (
+	priceComponentsOffset = 240
+	priceComponentSize    = 96
+
+	mappingProductsOffset = 56
+	productAttrsOffset    = 48
+)
+
+var (
+	ErrInvalidMagic          = errors.New("pyth: invalid magic")
+	ErrInvalidVersion        = errors.New("pyth: invalid version")
+	ErrInvalidAccountType    = errors.New("pyth: invalid account type")
+	ErrInvalidAccountSize    = errors.New("pyth: invalid account size")
+	ErrInvalidComponentCount = errors.New("pyth: invalid number of price components")
+	ErrInvalidProductCount   = errors.New("pyth: invalid number of mapping products")
+	ErrInvalidAttributes     = errors.New("pyth: invalid product attributes")
+)
+
 type PriceStatus uint32
 
 const (
@@ -59,6 +86,106 @@ type Base struct {
 	Size    uint32
 }
 
+type MappingData struct {
+	Base
+	NumProducts        uint32
+	NextMappingAccount solana.PublicKey
+	ProductAccounts    []solana.PublicKey
+}
+
+func ParseMappingData(data []byte) (*MappingData, error) {
+	base, err := parseBase(data, AccountTypeMapping, mappingProductsOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	numProducts := binary.LittleEndian.Uint32(data[16:20])
+	if numProducts > MaxMappingProducts || mappingProductsOffset+int(numProducts)*32 > len(data) {
+		return nil, ErrInvalidProductCount
+	}
+	products := make([]solana.PublicKey, numProducts)
+	for i := range products {
+		products[i] = solana.PublicKeyFromBytes(data[mappingProductsOffset+i*32 : mappingProductsOffset+(i+1)*32])
+	}
+
+	return &MappingData{
+		Base:               base,
+		NumProducts:        numProducts,
+		NextMappingAccount: solana.PublicKeyFromBytes(data[24:56]),
+		ProductAccounts:    products,
+	}, nil
+}
+
+type ProductData struct {
+	Base
+	// PriceAccount first price account of the product
+	PriceAccount solana.PublicKey
+	Attributes   map[string]string
+}
+
+func ParseProductData(data []byte) (*ProductData, error) {
+	base, err := parseBase(data, AccountTypeProduct, productAttrsOffset)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := parseAttributes(data[productAttrsOffset:base.Size])
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProductData{
+		Base:         base,
+		PriceAccount: solana.PublicKeyFromBytes(data[16:48]),
+		Attributes:   attributes,
+	}, nil
+}
+
+// parseAttributes decodes sequence of u8 length prefixed key and value strings
+func parseAttributes(data []byte) (map[string]string, error) {
+	attributes := make(map[string]string)
+	readString := func() (string, bool) {
+		if len(data) == 0 {
+			return "", false
+		}
+		n := int(data[0])
+		if len(data) < 1+n {
+			return "", false
+		}
+		value := string(data[1 : 1+n])
+		data = data[1+n:]
+		return value, true
+	}
+
+	for len(data) > 0 {
+		key, ok := readString()
+		if !ok {
+			return nil, ErrInvalidAttributes
+		}
+		value, ok := readString()
+		if !ok {
+			return nil, ErrInvalidAttributes
+		}
+		attributes[key] = value
+	}
+	return attributes, nil
+}
+
+func (p *ProductData) Symbol() string {
+	return p.Attributes["symbol"]
+}
+
+func (p *ProductData) AssetType() string {
+	return p.Attributes["asset_type"]
+}
+
+func (p *ProductData) BaseCurrency() string {
+	return p.Attributes["base"]
+}
+
+func (p *ProductData) QuoteCurrency() string {
+	return p.Attributes["quote_currency"]
+}
+
 type Ema struct {
 	ValueComponent int64
 	Value          float64
@@ -72,46 +199,133 @@ type PriceInfo struct {
 	ConfComponent  uint64
 	Conf           float64
 	Status         PriceStatus
+	CorpAction     CorpAction
 	PublishSlot    uint64
 }
 
+// PriceComponent is a publisher contribution, Agg is the quote used in the last aggregation and Latest is the newest quote
+type PriceComponent struct {
+	Publisher solana.PublicKey
+	Agg       PriceInfo
+	Latest    PriceInfo
+}
+
 type PriceData struct {
 	Base
-	PriceType     PriceType
-	Exponent      int32
-	LastSlot      uint64
-	ValidSlot     uint64
-	Timestamp     int64
-	PrevSlot      uint64
-	PrevPrice     int64
-	PrevConf      uint64
-	PrevTimestamp int64
-	Agg           PriceInfo
-}
-
-func ParsePriceData(data []byte) *PriceData {
-	magic := binary.LittleEndian.Uint32(data[0:4])
-	if magic != Magic {
-		panic("invalid magic")
+	PriceType         PriceType
+	Exponent          int32
+	NumComponentPrice uint32
+	NumQuoters        uint32
+	LastSlot          uint64
+	ValidSlot         uint64
+	EmaPrice          Ema
+	EmaConf           Ema
+	Timestamp         int64
+	MinPublishers     uint8
+	Drv2              int8
+	Drv3              int16
+	Drv4              int32
+	ProductAccount    solana.PublicKey
+	NextPriceAccount  solana.PublicKey
+	PrevSlot          uint64
+	PrevPrice         int64
+	PrevConf          uint64
+	PrevTimestamp     int64
+	Agg               PriceInfo
+	Components        []PriceComponent
+}
+
+func ParsePriceData(data []byte) (*PriceData, error) {
+	base, err := parseBase(data, AccountTypePrice, priceComponentsOffset)
+	if err != nil {
+		return nil, err
 	}
+
 	exponent := int32(binary.LittleEndian.Uint32(data[20:24]))
+	numComponentPrice := binary.LittleEndian.Uint32(data[24:28])
+	if numComponentPrice > MaxPriceComponents || priceComponentsOffset+int(numComponentPrice)*priceComponentSize > len(data) {
+		return nil, ErrInvalidComponentCount
+	}
+
+	components := make([]PriceComponent, numComponentPrice)
+	for i := range components {
+		componentData := data[priceComponentsOffset+i*priceComponentSize : priceComponentsOffset+(i+1)*priceComponentSize]
+		components[i] = PriceComponent{
+			Publisher: solana.PublicKeyFromBytes(componentData[0:32]),
+			Agg:       ParsePriceInfo(componentData[32:64], exponent),
+			Latest:    ParsePriceInfo(componentData[64:96], exponent),
+		}
+	}
+
 	return &PriceData{
-		Base: Base{
-			Magic:   magic,
-			Version: binary.LittleEndian.Uint32(data[4:8]),
-			Type:    AccountType(binary.LittleEndian.Uint32(data[8:12])),
-			Size:    binary.LittleEndian.Uint32(data[12:16]),
-		},
-		PriceType:     PriceType(binary.LittleEndian.Uint32(data[16:20])),
-		Exponent:      exponent,
-		LastSlot:      binary.LittleEndian.Uint64(data[32:40]),
-		ValidSlot:     binary.LittleEndian.Uint64(data[40:48]),
-		Timestamp:     int64(binary.LittleEndian.Uint64(data[96:104])),
-		PrevSlot:      binary.LittleEndian.Uint64(data[176:184]),
-		PrevPrice:     int64(binary.LittleEndian.Uint64(data[184:192])),
-		PrevConf:      binary.LittleEndian.Uint64(data[192:200]),
-		PrevTimestamp: int64(binary.LittleEndian.Uint64(data[200:208])),
-		Agg:           ParsePriceInfo(data[208:240], exponent),
+		Base:              base,
+		PriceType:         PriceType(binary.LittleEndian.Uint32(data[16:20])),
+		Exponent:          exponent,
+		NumComponentPrice: numComponentPrice,
+		NumQuoters:        binary.LittleEndian.Uint32(data[28:32]),
+		LastSlot:          binary.LittleEndian.Uint64(data[32:40]),
+		ValidSlot:         binary.LittleEndian.Uint64(data[40:48]),
+		EmaPrice:          ParseEma(data[48:72], exponent),
+		EmaConf:           ParseEma(data[72:96], exponent),
+		Timestamp:         int64(binary.LittleEndian.Uint64(data[96:104])),
+		MinPublishers:     data[104],
+		Drv2:              int8(data[105]),
+		Drv3:              int16(binary.LittleEndian.Uint16(data[106:108])),
+		Drv4:              int32(binary.LittleEndian.Uint32(data[108:112])),
+		ProductAccount:    solana.PublicKeyFromBytes(data[112:144]),
+		NextPriceAccount:  solana.PublicKeyFromBytes(data[144:176]),
+		PrevSlot:          binary.LittleEndian.Uint64(data[176:184]),
+		PrevPrice:         int64(binary.LittleEndian.Uint64(data[184:192])),
+		PrevConf:          binary.LittleEndian.Uint64(data[192:200]),
+		PrevTimestamp:     int64(binary.LittleEndian.Uint64(data[200:208])),
+		Agg:               ParsePriceInfo(data[208:240], exponent),
+		Components:        components,
+	}, nil
+}
+
+// parseBase validates account header, minSize is the fixed part of the account type
+func parseBase(data []byte, accountType AccountType, minSize int) (Base, error) {
+	if len(data) < minSize {
+		return Base{}, ErrInvalidAccountSize
+	}
+	base := Base{
+		Magic:   binary.LittleEndian.Uint32(data[0:4]),
+		Version: binary.LittleEndian.Uint32(data[4:8]),
+		Type:    AccountType(binary.LittleEndian.Uint32(data[8:12])),
+		Size:    binary.LittleEndian.Uint32(data[12:16]),
+	}
+	if base.Magic != Magic {
+		return Base{}, ErrInvalidMagic
+	}
+	if base.Version != Version {
+		return Base{}, ErrInvalidVersion
+	}
+	if base.Type != accountType {
+		return Base{}, ErrInvalidAccountType
+	}
+	if int(base.Size) < minSize || int(base.Size) > len(data) {
+		return Base{}, ErrInvalidAccountSize
+	}
+	return base, nil
+}
+
+// ComponentByPublisher returns quote of the publisher, nil if it doesn't publish to this price account
+func (p *PriceData) ComponentByPublisher(publisher solana.PublicKey) *PriceComponent {
+	for i := range p.Components {
+		if p.Components[i].Publisher == publisher {
+			return &p.Components[i]
+		}
+	}
+	return nil
+}
+
+func ParseEma(data []byte, exponent int32) Ema {
+	valueComponent := int64(binary.LittleEndian.Uint64(data[0:8]))
+	return Ema{
+		ValueComponent: valueComponent,
+		Value:          float64(valueComponent) * math.Pow10(int(exponent)),
+		Numerator:      int64(binary.LittleEndian.Uint64(data[8:16])),
+		Denominator:    int64(binary.LittleEndian.Uint64(data[16:24])),
 	}
 }
 
@@ -124,6 +338,7 @@ func ParsePriceInfo(data []byte, exponent int32) PriceInfo {
 		ConfComponent:  confComponent,
 		Conf:           float64(confComponent) * math.Pow10(int(exponent)),
 		Status:         PriceStatus(binary.LittleEndian.Uint32(data[16:20])),
+		CorpAction:     CorpAction(binary.LittleEndian.Uint32(data[20:24])),
 		PublishSlot:    binary.LittleEndian.Uint64(data[24:32]),
 	}
 }
@@ -0,0 +1,184 @@
+package pyth
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+type testQuote struct {
+	publisher solana.PublicKey
+	price     int64
+	conf      uint64
+	status    PriceStatus
+	slot      uint64
+}
+
+func putPriceInfo(data []byte, price int64, conf uint64, status PriceStatus, slot uint64) {
+	binary.LittleEndian.PutUint64(data[0:8], uint64(price))
+	binary.LittleEndian.PutUint64(data[8:16], conf)
+	binary.LittleEndian.PutUint32(data[16:20], uint32(status))
+	binary.LittleEndian.PutUint64(data[24:32], slot)
+}
+
+// newTestPriceAccount builds price account data with exponent -8, quotes are used for both agg and latest
+func newTestPriceAccount(quotes ...testQuote) []byte {
+	data := make([]byte, priceComponentsOffset+MaxPriceComponents*priceComponentSize)
+	binary.LittleEndian.PutUint32(data[0:4], Magic)
+	binary.LittleEndian.PutUint32(data[4:8], Version)
+	binary.LittleEndian.PutUint32(data[8:12], AccountTypePrice)
+	binary.LittleEndian.PutUint32(data[12:16], uint32(priceComponentsOffset+len(quotes)*priceComponentSize))
+	binary.LittleEndian.PutUint32(data[16:20], PriceTypePrice)
+	binary.LittleEndian.PutUint32(data[20:24], uint32(0xfffffff8)) // -8
+	binary.LittleEndian.PutUint32(data[24:28], uint32(len(quotes)))
+	binary.LittleEndian.PutUint32(data[28:32], uint32(len(quotes)))
+	for i, quote := range quotes {
+		component := data[priceComponentsOffset+i*priceComponentSize:]
+		copy(component[0:32], quote.publisher[:])
+		putPriceInfo(component[32:64], quote.price, quote.conf, quote.status, quote.slot)
+		putPriceInfo(component[64:96], quote.price, quote.conf, quote.status, quote.slot)
+	}
+	return data
+}
+
+func TestParsePriceData(t *testing.T) {
+	publisher := solana.NewWallet().PublicKey()
+	data := newTestPriceAccount(
+		testQuote{publisher: publisher, price: 150_00000000, conf: 5000000, status: PriceStatusTrading, slot: 100},
+		testQuote{publisher: solana.NewWallet().PublicKey(), price: 151_00000000, conf: 6000000, status: PriceStatusTrading, slot: 99},
+	)
+	binary.LittleEndian.PutUint64(data[32:40], 100)
+	binary.LittleEndian.PutUint64(data[48:56], 149_50000000) // ema price
+	binary.LittleEndian.PutUint64(data[72:80], 4000000)      // ema conf
+	data[104] = 3                                            // min pub
+	product := solana.NewWallet().PublicKey()
+	copy(data[112:144], product[:])
+	putPriceInfo(data[208:240], 150_50000000, 5500000, PriceStatusTrading, 100)
+
+	price, err := ParsePriceData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Exponent != -8 || price.NumComponentPrice != 2 || price.NumQuoters != 2 || price.LastSlot != 100 || price.MinPublishers != 3 {
+		t.Fatalf("unexpected header %+v", price)
+	}
+	if price.ProductAccount != product || !price.NextPriceAccount.IsZero() {
+		t.Fatalf("unexpected pointers %s %s", price.ProductAccount, price.NextPriceAccount)
+	}
+	if math.Abs(price.EmaPrice.Value-149.5) > 1e-9 || math.Abs(price.EmaConf.Value-0.04) > 1e-9 {
+		t.Fatalf("unexpected ema %+v %+v", price.EmaPrice, price.EmaConf)
+	}
+	if math.Abs(price.Agg.Price-150.5) > 1e-9 || price.Agg.Status != PriceStatusTrading || price.Agg.PublishSlot != 100 {
+		t.Fatalf("unexpected agg %+v", price.Agg)
+	}
+	if len(price.Components) != 2 {
+		t.Fatalf("expected 2 components, got %d", len(price.Components))
+	}
+	component := price.ComponentByPublisher(publisher)
+	if component == nil || component.Latest.PriceComponent != 150_00000000 || component.Agg.PublishSlot != 100 {
+		t.Fatalf("unexpected component %+v", component)
+	}
+	if price.ComponentByPublisher(solana.NewWallet().PublicKey()) != nil {
+		t.Fatal("unknown publisher should have no component")
+	}
+}
+
+func TestParsePriceDataErrors(t *testing.T) {
+	valid := newTestPriceAccount(testQuote{price: 1, status: PriceStatusTrading})
+
+	corrupt := func(f func(data []byte)) []byte {
+		data := append([]byte{}, valid...)
+		f(data)
+		return data
+	}
+
+	cases := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"short", valid[:100], ErrInvalidAccountSize},
+		{"magic", corrupt(func(data []byte) { data[0] ^= 0xff }), ErrInvalidMagic},
+		{"version", corrupt(func(data []byte) { binary.LittleEndian.PutUint32(data[4:8], 1) }), ErrInvalidVersion},
+		{"type", corrupt(func(data []byte) { binary.LittleEndian.PutUint32(data[8:12], AccountTypeProduct) }), ErrInvalidAccountType},
+		{"size", corrupt(func(data []byte) { binary.LittleEndian.PutUint32(data[12:16], uint32(len(valid)+1)) }), ErrInvalidAccountSize},
+		{"components", corrupt(func(data []byte) { binary.LittleEndian.PutUint32(data[24:28], MaxPriceComponents+1) }), ErrInvalidComponentCount},
+	}
+	for _, c := range cases {
+		if _, err := ParsePriceData(c.data); err != c.err {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func putAttributes(data []byte, attributes ...string) int {
+	offset := 0
+	for _, attribute := range attributes {
+		data[offset] = byte(len(attribute))
+		copy(data[offset+1:], attribute)
+		offset += 1 + len(attribute)
+	}
+	return offset
+}
+
+func TestParseMappingAndProduct(t *testing.T) {
+	productPK, nextPK := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mapping := make([]byte, mappingProductsOffset+MaxMappingProducts*32)
+	binary.LittleEndian.PutUint32(mapping[0:4], Magic)
+	binary.LittleEndian.PutUint32(mapping[4:8], Version)
+	binary.LittleEndian.PutUint32(mapping[8:12], AccountTypeMapping)
+	binary.LittleEndian.PutUint32(mapping[12:16], mappingProductsOffset+32)
+	binary.LittleEndian.PutUint32(mapping[16:20], 1)
+	copy(mapping[24:56], nextPK[:])
+	copy(mapping[mappingProductsOffset:], productPK[:])
+
+	mappingData, err := ParseMappingData(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappingData.ProductAccounts) != 1 || mappingData.ProductAccounts[0] != productPK || mappingData.NextMappingAccount != nextPK {
+		t.Fatalf("unexpected mapping %+v", mappingData)
+	}
+	binary.LittleEndian.PutUint32(mapping[16:20], MaxMappingProducts+1)
+	if _, err := ParseMappingData(mapping); err != ErrInvalidProductCount {
+		t.Fatalf("expected ErrInvalidProductCount, got %v", err)
+	}
+
+	pricePK := solana.NewWallet().PublicKey()
+	product := make([]byte, 512)
+	binary.LittleEndian.PutUint32(product[0:4], Magic)
+	binary.LittleEndian.PutUint32(product[4:8], Version)
+	binary.LittleEndian.PutUint32(product[8:12], AccountTypeProduct)
+	copy(product[16:48], pricePK[:])
+	attrsSize := putAttributes(product[productAttrsOffset:],
+		"symbol", "Crypto.SOL/USD", "asset_type", "Crypto", "base", "SOL", "quote_currency", "USD")
+	binary.LittleEndian.PutUint32(product[12:16], uint32(productAttrsOffset+attrsSize))
+
+	productData, err := ParseProductData(product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if productData.PriceAccount != pricePK || productData.Symbol() != "Crypto.SOL/USD" || productData.AssetType() != "Crypto" ||
+		productData.BaseCurrency() != "SOL" || productData.QuoteCurrency() != "USD" {
+		t.Fatalf("unexpected product %+v", productData)
+	}
+
+	// attribute value length runs past account size
+	binary.LittleEndian.PutUint32(product[12:16], uint32(productAttrsOffset+attrsSize-1))
+	if _, err := ParseProductData(product); err != ErrInvalidAttributes {
+		t.Fatalf("expected ErrInvalidAttributes, got %v", err)
+	}
+
+	registry := NewSymbolRegistry([]*Product{{Address: productPK, PriceAccount: pricePK, Attributes: productData.Attributes}})
+	if priceAccount, err := registry.PriceAccount("crypto.sol/usd"); err != nil || priceAccount != pricePK {
+		t.Fatalf("unexpected lookup %s %v", priceAccount, err)
+	}
+	if _, err := registry.PriceAccount("Crypto.BTC/USD"); err != ErrSymbolNotFound {
+		t.Fatalf("expected ErrSymbolNotFound, got %v", err)
+	}
+	if p, ok := registry.ProductByPriceAccount(pricePK); !ok || p.Address != productPK {
+		t.Fatal("reverse lookup failed")
+	}
+}
@@ -0,0 +1,113 @@
+package pyth
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// WithPendingUpdates returns a copy of the components with latest quotes replaced by pending publisher updates,
+// updates for other price accounts, unknown publishers or not newer than the stored quote are ignored like the program does
+func (p *PriceData) WithPendingUpdates(priceAccount solana.PublicKey, updates ...*UpdPriceInstruction) []PriceComponent {
+	components := make([]PriceComponent, len(p.Components))
+	copy(components, p.Components)
+	for _, update := range updates {
+		if update == nil || update.PriceAccount != priceAccount {
+			continue
+		}
+		for i := range components {
+			if components[i].Publisher != update.Publisher || update.PublishSlot <= components[i].Latest.PublishSlot {
+				continue
+			}
+			components[i].Latest = PriceInfo{
+				PriceComponent: update.Price,
+				Price:          float64(update.Price) * math.Pow10(int(p.Exponent)),
+				ConfComponent:  update.Conf,
+				Conf:           float64(update.Conf) * math.Pow10(int(p.Exponent)),
+				Status:         update.Status,
+				PublishSlot:    update.PublishSlot,
+			}
+		}
+	}
+	return components
+}
+
+// PredictAggregate is the aggregate the first update in slot will compute once the pending updates landed,
+// the program aggregates latest quotes before storing the quote of the instruction that triggered it
+func (p *PriceData) PredictAggregate(priceAccount solana.PublicKey, slot uint64, updates ...*UpdPriceInstruction) (PriceInfo, uint32) {
+	return AggregateComponents(p.WithPendingUpdates(priceAccount, updates...), p.MinPublishers, p.Exponent, slot)
+}
+
+// AggregateComponents is a port of oracle upd_aggregate, every valid quote contributes price - conf, price and price + conf,
+// aggregate price is the median and confidence the wider of the distances to p25 and p75.
+// Returns the aggregate and number of valid quotes, status is unknown when there are fewer than min publishers
+func AggregateComponents(components []PriceComponent, minPublishers uint8, exponent int32, slot uint64) (PriceInfo, uint32) {
+	prices := make([]int64, 0, 3*len(components))
+	numQuoters := uint32(0)
+	for _, component := range components {
+		quote := component.Latest
+		price, conf := quote.PriceComponent, int64(quote.ConfComponent)
+		if quote.Status != PriceStatusTrading || quote.PublishSlot > slot || slot-quote.PublishSlot > MaxSlotDifference {
+			continue
+		}
+		// guards price - conf and price + conf against overflow
+		if conf <= 0 || price < math.MinInt64+conf || price > math.MaxInt64-conf {
+			continue
+		}
+		numQuoters++
+		prices = append(prices, price-conf, price, price+conf)
+	}
+
+	if numQuoters == 0 || numQuoters < uint32(minPublishers) {
+		return PriceInfo{Status: PriceStatusUnknown}, numQuoters
+	}
+
+	p25, p50, p75 := priceModel(prices)
+	conf := max(p50-p25, p75-p50)
+	if conf <= 0 {
+		return PriceInfo{Status: PriceStatusUnknown}, numQuoters
+	}
+
+	return PriceInfo{
+		PriceComponent: p50,
+		Price:          float64(p50) * math.Pow10(int(exponent)),
+		ConfComponent:  uint64(conf),
+		Conf:           float64(conf) * math.Pow10(int(exponent)),
+		Status:         PriceStatusTrading,
+		PublishSlot:    slot,
+	}, numQuoters
+}
+
+// priceModel p25, median and p75 of the quotes, even counts average the two middle values like price_model_core
+func priceModel(prices []int64) (p25, p50, p75 int64) {
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+	n := len(prices)
+
+	i := n >> 2
+	if n&2 != 0 {
+		p25 = prices[i]
+	} else {
+		p25 = avg2(prices[i-1], prices[i])
+	}
+
+	i = n >> 1
+	if n&1 != 0 {
+		p50 = prices[i]
+	} else {
+		p50 = avg2(prices[i-1], prices[i])
+	}
+
+	i = n - 1 - (n >> 2)
+	if n&2 != 0 {
+		p75 = prices[i]
+	} else {
+		p75 = avg2(prices[i], prices[i+1])
+	}
+	return
+}
+
+// avg2 average without overflow
+func avg2(x, y int64) int64 {
+	return (x >> 1) + (y >> 1) + (x & y & 1)
+}
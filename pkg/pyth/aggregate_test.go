@@ -0,0 +1,86 @@
+package pyth
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestAggregateComponents(t *testing.T) {
+	publishers := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+	priceData, err := ParsePriceData(newTestPriceAccount(
+		testQuote{publisher: publishers[0], price: 100, conf: 10, status: PriceStatusTrading, slot: 100},
+		testQuote{publisher: publishers[1], price: 110, conf: 10, status: PriceStatusTrading, slot: 100},
+		testQuote{publisher: publishers[2], price: 200, conf: 20, status: PriceStatusTrading, slot: 100},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// quotes 90 100 100 110 110 120 180 200 220
+	// p25 = (100 + 100) / 2, median 110, p75 = (180 + 200) / 2
+	aggregate, numQuoters := AggregateComponents(priceData.Components, 1, priceData.Exponent, 101)
+	if numQuoters != 3 || aggregate.Status != PriceStatusTrading || aggregate.PriceComponent != 110 || aggregate.ConfComponent != 80 || aggregate.PublishSlot != 101 {
+		t.Fatalf("unexpected aggregate %+v, quoters %d", aggregate, numQuoters)
+	}
+
+	// quotes older than max slot difference are ignored
+	aggregate, numQuoters = AggregateComponents(priceData.Components, 1, priceData.Exponent, 100+MaxSlotDifference+1)
+	if numQuoters != 0 || aggregate.Status != PriceStatusUnknown {
+		t.Fatalf("expected stale quotes to be ignored, got %+v", aggregate)
+	}
+
+	// not enough publishers
+	aggregate, _ = AggregateComponents(priceData.Components, 4, priceData.Exponent, 101)
+	if aggregate.Status != PriceStatusUnknown {
+		t.Fatalf("expected unknown status below min publishers, got %+v", aggregate)
+	}
+}
+
+func TestPredictAggregate(t *testing.T) {
+	priceAccount := solana.NewWallet().PublicKey()
+	publishers := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+	priceData, err := ParsePriceData(newTestPriceAccount(
+		testQuote{publisher: publishers[0], price: 100, conf: 10, status: PriceStatusTrading, slot: 100},
+		testQuote{publisher: publishers[1], price: 100, conf: 10, status: PriceStatusTrading, slot: 100},
+		testQuote{publisher: publishers[2], price: 100, conf: 10, status: PriceStatusHalted, slot: 100},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggregate, numQuoters := priceData.PredictAggregate(priceAccount, 102,
+		&UpdPriceInstruction{Publisher: publishers[1], PriceAccount: priceAccount, Status: PriceStatusTrading, Price: 80, Conf: 4, PublishSlot: 101},
+		&UpdPriceInstruction{Publisher: publishers[2], PriceAccount: priceAccount, Status: PriceStatusTrading, Price: 82, Conf: 4, PublishSlot: 101},
+		// other price account and older quote are ignored
+		&UpdPriceInstruction{Publisher: publishers[0], PriceAccount: solana.NewWallet().PublicKey(), Status: PriceStatusTrading, Price: 1, Conf: 1, PublishSlot: 101},
+		&UpdPriceInstruction{Publisher: publishers[0], PriceAccount: priceAccount, Status: PriceStatusTrading, Price: 1, Conf: 1, PublishSlot: 99},
+	)
+	// quotes 76 78 80 82 84 86 90 100 110
+	if numQuoters != 3 || aggregate.Status != PriceStatusTrading || aggregate.PriceComponent != 84 {
+		t.Fatalf("unexpected aggregate %+v, quoters %d", aggregate, numQuoters)
+	}
+	// p25 = (78 + 80) / 2, p75 = (90 + 100) / 2
+	if aggregate.ConfComponent != 11 {
+		t.Fatalf("unexpected confidence %d", aggregate.ConfComponent)
+	}
+	if priceData.Components[1].Latest.PriceComponent != 100 {
+		t.Fatal("pending updates must not modify price data")
+	}
+}
+
+func TestPriceModel(t *testing.T) {
+	for _, test := range []struct {
+		prices        []int64
+		p25, p50, p75 int64
+	}{
+		{[]int64{3, 1, 2}, 1, 2, 3},
+		{[]int64{1, 2, 3, 4, 5, 6}, 2, 3, 5},
+		{[]int64{-3, -1, -2}, -3, -2, -1},
+	} {
+		p25, p50, p75 := priceModel(test.prices)
+		if p25 != test.p25 || p50 != test.p50 || p75 != test.p75 {
+			t.Fatalf("%v: got %d %d %d", test.prices, p25, p50, p75)
+		}
+	}
+}
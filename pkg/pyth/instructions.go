@@ -1,6 +1,13 @@
 package pyth
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ProgramAddress = solana.MustPublicKeyFromBase58("FsJ3A3u2vn5cTVofAjvy6y5kwABJAqYWpe4975bi2epH")
 
 const (
 	Instruction_InitMapping = uint32(iota)
@@ -20,18 +27,302 @@ const (
 	instruction_count // number of different instruction types
 )
 
+var (
+	ErrInvalidInstructionData = errors.New("pyth: invalid instruction data")
+	ErrUnknownInstruction     = errors.New("pyth: unknown instruction")
+	ErrNotEnoughAccounts      = errors.New("pyth: not enough instruction accounts")
+)
+
+const (
+	// instructionHeaderSize version + command
+	instructionHeaderSize = 8
+	// upd_price header + status + unused + price + conf + publishing slot
+	updPriceInstructionSize = instructionHeaderSize + 4 + 4 + 8 + 8 + 8
+	// add_price and init_price header + exponent + price type
+	initPriceInstructionSize = instructionHeaderSize + 4 + 4
+	// add_publisher and del_publisher header + publisher
+	publisherInstructionSize = instructionHeaderSize + 32
+	// set_min_pub header + min publishers + padding
+	setMinPubInstructionSize = instructionHeaderSize + 4
+	// upd_test header + number of quotes + exponent + slot diffs + prices + confidences
+	updTestInstructionSize = instructionHeaderSize + 4 + 4 + MaxPriceComponents + MaxPriceComponents*8 + MaxPriceComponents*8
+)
+
+// Instruction is one of the typed *Instruction structs below
+type Instruction interface {
+	Command() uint32
+}
+
+type InitMappingInstruction struct {
+	Funding, Mapping solana.PublicKey
+}
+
+type AddMappingInstruction struct {
+	Funding, TailMapping, NewMapping solana.PublicKey
+}
+
+type AddProductInstruction struct {
+	Funding, Mapping, NewProduct solana.PublicKey
+}
+
+type UpdProductInstruction struct {
+	Funding, Product solana.PublicKey
+	// Attributes raw key value pairs as stored in the product account
+	Attributes map[string]string
+}
+
+type AddPriceInstruction struct {
+	Funding, Product, NewPrice solana.PublicKey
+	Exponent                   int32
+	PriceType                  PriceType
+}
+
+type AddPublisherInstruction struct {
+	Funding, PriceAccount, Publisher solana.PublicKey
+}
+
+type DelPublisherInstruction struct {
+	Funding, PriceAccount, Publisher solana.PublicKey
+}
+
+// UpdPriceInstruction is a publisher quote, also used for upd_price_no_fail_on_error and agg_price
+type UpdPriceInstruction struct {
+	Publisher, PriceAccount solana.PublicKey
+	Status                  PriceStatus
+	Price                   int64
+	Conf                    uint64
+	PublishSlot             uint64
+	// NoFailOnError upd_price_no_fail_on_error variant
+	NoFailOnError bool
+}
+
+type AggPriceInstruction struct {
+	Funding, PriceAccount solana.PublicKey
+}
+
+type InitPriceInstruction struct {
+	Funding, PriceAccount solana.PublicKey
+	Exponent              int32
+	PriceType             PriceType
+}
+
+type InitTestInstruction struct {
+	Funding, TestAccount solana.PublicKey
+}
+
+type UpdTestInstruction struct {
+	Funding, TestAccount solana.PublicKey
+	Exponent             int32
+	SlotDiffs            []int8
+	Prices               []int64
+	Confs                []uint64
+}
+
+type SetMinPubInstruction struct {
+	Funding, PriceAccount solana.PublicKey
+	MinPublishers         uint8
+}
+
+func (*InitMappingInstruction) Command() uint32  { return Instruction_InitMapping }
+func (*AddMappingInstruction) Command() uint32   { return Instruction_AddMapping }
+func (*AddProductInstruction) Command() uint32   { return Instruction_AddProduct }
+func (*UpdProductInstruction) Command() uint32   { return Instruction_UpdProduct }
+func (*AddPriceInstruction) Command() uint32     { return Instruction_AddPrice }
+func (*AddPublisherInstruction) Command() uint32 { return Instruction_AddPublisher }
+func (*DelPublisherInstruction) Command() uint32 { return Instruction_DelPublisher }
+func (*AggPriceInstruction) Command() uint32     { return Instruction_AggPrice }
+func (*InitPriceInstruction) Command() uint32    { return Instruction_InitPrice }
+func (*InitTestInstruction) Command() uint32     { return Instruction_InitTest }
+func (*UpdTestInstruction) Command() uint32      { return Instruction_UpdTest }
+func (*SetMinPubInstruction) Command() uint32    { return Instruction_SetMinPub }
+
+func (i *UpdPriceInstruction) Command() uint32 {
+	if i.NoFailOnError {
+		return Instruction_UpdPriceNoFailOnError
+	}
+	return Instruction_UpdPrice
+}
+
+// ParseInstructionHeader returns command of the instruction, version is checked
+func ParseInstructionHeader(data []byte) (uint32, error) {
+	if len(data) < instructionHeaderSize {
+		return 0, ErrInvalidInstructionData
+	}
+	if binary.LittleEndian.Uint32(data[0:4]) != Version {
+		return 0, ErrInvalidVersion
+	}
+	command := binary.LittleEndian.Uint32(data[4:8])
+	if command >= instruction_count {
+		return 0, ErrUnknownInstruction
+	}
+	return command, nil
+}
+
 func IsUpdatePriceInstruction(data []byte) bool {
-	version := binary.LittleEndian.Uint32(data[0:4])
-	ixNumber := binary.LittleEndian.Uint32(data[4:8])
-	if version != Version {
+	command, err := ParseInstructionHeader(data)
+	if err != nil {
 		return false
 	}
+	return command == Instruction_UpdPrice || command == Instruction_UpdPriceNoFailOnError
+}
 
-	return ixNumber == Instruction_UpdPrice || ixNumber == Instruction_UpdPriceNoFailOnError
+// ParseUpdatePriceInstruction decodes upd_price and upd_price_no_fail_on_error, accounts are [publisher, price, clock]
+func ParseUpdatePriceInstruction(accounts []solana.PublicKey, data []byte) (*UpdPriceInstruction, error) {
+	if !IsUpdatePriceInstruction(data) {
+		return nil, ErrUnknownInstruction
+	}
+	instruction, err := ParseInstruction(accounts, data)
+	if err != nil {
+		return nil, err
+	}
+	return instruction.(*UpdPriceInstruction), nil
 }
 
-func ParseUpdatePriceInstruction(data []byte) (price int64, conf uint64) {
-	price = int64(binary.LittleEndian.Uint64(data[16:24]))
-	conf = binary.LittleEndian.Uint64(data[24:32])
-	return
+// ParseInstruction decodes any oracle program instruction, accounts are instruction accounts in order
+func ParseInstruction(accounts []solana.PublicKey, data []byte) (Instruction, error) {
+	command, err := ParseInstructionHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch command {
+	case Instruction_InitMapping:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		return &InitMappingInstruction{Funding: accounts[0], Mapping: accounts[1]}, nil
+
+	case Instruction_AddMapping:
+		if len(accounts) < 3 {
+			return nil, ErrNotEnoughAccounts
+		}
+		return &AddMappingInstruction{Funding: accounts[0], TailMapping: accounts[1], NewMapping: accounts[2]}, nil
+
+	case Instruction_AddProduct:
+		if len(accounts) < 3 {
+			return nil, ErrNotEnoughAccounts
+		}
+		return &AddProductInstruction{Funding: accounts[0], Mapping: accounts[1], NewProduct: accounts[2]}, nil
+
+	case Instruction_UpdProduct:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		attributes, err := parseAttributes(data[instructionHeaderSize:])
+		if err != nil {
+			return nil, err
+		}
+		return &UpdProductInstruction{Funding: accounts[0], Product: accounts[1], Attributes: attributes}, nil
+
+	case Instruction_AddPrice:
+		if len(accounts) < 3 {
+			return nil, ErrNotEnoughAccounts
+		}
+		if len(data) < initPriceInstructionSize {
+			return nil, ErrInvalidInstructionData
+		}
+		return &AddPriceInstruction{
+			Funding:   accounts[0],
+			Product:   accounts[1],
+			NewPrice:  accounts[2],
+			Exponent:  int32(binary.LittleEndian.Uint32(data[8:12])),
+			PriceType: PriceType(binary.LittleEndian.Uint32(data[12:16])),
+		}, nil
+
+	case Instruction_AddPublisher, Instruction_DelPublisher:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		if len(data) < publisherInstructionSize {
+			return nil, ErrInvalidInstructionData
+		}
+		publisher := solana.PublicKeyFromBytes(data[8:40])
+		if command == Instruction_DelPublisher {
+			return &DelPublisherInstruction{Funding: accounts[0], PriceAccount: accounts[1], Publisher: publisher}, nil
+		}
+		return &AddPublisherInstruction{Funding: accounts[0], PriceAccount: accounts[1], Publisher: publisher}, nil
+
+	case Instruction_UpdPrice, Instruction_UpdPriceNoFailOnError:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		if len(data) < updPriceInstructionSize {
+			return nil, ErrInvalidInstructionData
+		}
+		return &UpdPriceInstruction{
+			Publisher:     accounts[0],
+			PriceAccount:  accounts[1],
+			Status:        PriceStatus(binary.LittleEndian.Uint32(data[8:12])),
+			Price:         int64(binary.LittleEndian.Uint64(data[16:24])),
+			Conf:          binary.LittleEndian.Uint64(data[24:32]),
+			PublishSlot:   binary.LittleEndian.Uint64(data[32:40]),
+			NoFailOnError: command == Instruction_UpdPriceNoFailOnError,
+		}, nil
+
+	case Instruction_AggPrice:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		return &AggPriceInstruction{Funding: accounts[0], PriceAccount: accounts[1]}, nil
+
+	case Instruction_InitPrice:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		if len(data) < initPriceInstructionSize {
+			return nil, ErrInvalidInstructionData
+		}
+		return &InitPriceInstruction{
+			Funding:      accounts[0],
+			PriceAccount: accounts[1],
+			Exponent:     int32(binary.LittleEndian.Uint32(data[8:12])),
+			PriceType:    PriceType(binary.LittleEndian.Uint32(data[12:16])),
+		}, nil
+
+	case Instruction_InitTest:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		return &InitTestInstruction{Funding: accounts[0], TestAccount: accounts[1]}, nil
+
+	case Instruction_UpdTest:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		if len(data) < updTestInstructionSize {
+			return nil, ErrInvalidInstructionData
+		}
+		num := binary.LittleEndian.Uint32(data[8:12])
+		if num > MaxPriceComponents {
+			return nil, ErrInvalidComponentCount
+		}
+		instruction := &UpdTestInstruction{
+			Funding:     accounts[0],
+			TestAccount: accounts[1],
+			Exponent:    int32(binary.LittleEndian.Uint32(data[12:16])),
+			SlotDiffs:   make([]int8, num),
+			Prices:      make([]int64, num),
+			Confs:       make([]uint64, num),
+		}
+		slotDiffsOffset := 16
+		pricesOffset := slotDiffsOffset + MaxPriceComponents
+		confsOffset := pricesOffset + MaxPriceComponents*8
+		for i := 0; i < int(num); i++ {
+			instruction.SlotDiffs[i] = int8(data[slotDiffsOffset+i])
+			instruction.Prices[i] = int64(binary.LittleEndian.Uint64(data[pricesOffset+i*8:]))
+			instruction.Confs[i] = binary.LittleEndian.Uint64(data[confsOffset+i*8:])
+		}
+		return instruction, nil
+
+	case Instruction_SetMinPub:
+		if len(accounts) < 2 {
+			return nil, ErrNotEnoughAccounts
+		}
+		if len(data) < setMinPubInstructionSize {
+			return nil, ErrInvalidInstructionData
+		}
+		return &SetMinPubInstruction{Funding: accounts[0], PriceAccount: accounts[1], MinPublishers: data[8]}, nil
+	}
+
+	return nil, ErrUnknownInstruction
 }
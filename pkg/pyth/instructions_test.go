@@ -0,0 +1,125 @@
+package pyth
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func newInstructionData(command uint32, size int) []byte {
+	data := make([]byte, size)
+	binary.LittleEndian.PutUint32(data[0:4], Version)
+	binary.LittleEndian.PutUint32(data[4:8], command)
+	return data
+}
+
+func TestParseUpdatePriceInstruction(t *testing.T) {
+	publisher, priceAccount := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	data := newInstructionData(Instruction_UpdPriceNoFailOnError, updPriceInstructionSize)
+	binary.LittleEndian.PutUint32(data[8:12], PriceStatusTrading)
+	price := int64(-150_000_000)
+	binary.LittleEndian.PutUint64(data[16:24], uint64(price))
+	binary.LittleEndian.PutUint64(data[24:32], 12_345)
+	binary.LittleEndian.PutUint64(data[32:40], 280_000_000)
+
+	update, err := ParseUpdatePriceInstruction([]solana.PublicKey{publisher, priceAccount, solana.SysVarClockPubkey}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Publisher != publisher || update.PriceAccount != priceAccount {
+		t.Fatalf("unexpected accounts %+v", update)
+	}
+	if update.Status != PriceStatusTrading || update.Price != -150_000_000 || update.Conf != 12_345 || update.PublishSlot != 280_000_000 {
+		t.Fatalf("unexpected update %+v", update)
+	}
+	if !update.NoFailOnError || update.Command() != Instruction_UpdPriceNoFailOnError {
+		t.Fatal("expected no fail on error variant")
+	}
+}
+
+func TestParseInstructionShortData(t *testing.T) {
+	accounts := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+	for _, command := range []uint32{Instruction_AddPrice, Instruction_AddPublisher, Instruction_DelPublisher, Instruction_UpdPrice, Instruction_InitPrice, Instruction_UpdTest, Instruction_SetMinPub} {
+		data := newInstructionData(command, instructionHeaderSize)
+		if _, err := ParseInstruction(accounts, data); err != ErrInvalidInstructionData {
+			t.Fatalf("command %d: expected ErrInvalidInstructionData, got %v", command, err)
+		}
+	}
+	for size := 0; size < instructionHeaderSize; size++ {
+		if IsUpdatePriceInstruction(make([]byte, size)) {
+			t.Fatalf("short data of %d bytes detected as update", size)
+		}
+		if _, err := ParseInstruction(accounts, make([]byte, size)); err != ErrInvalidInstructionData {
+			t.Fatalf("expected ErrInvalidInstructionData for %d bytes, got %v", size, err)
+		}
+	}
+	if _, err := ParseInstruction(accounts[:1], newInstructionData(Instruction_UpdPrice, updPriceInstructionSize)); err != ErrNotEnoughAccounts {
+		t.Fatalf("expected ErrNotEnoughAccounts, got %v", err)
+	}
+	if _, err := ParseInstruction(accounts, newInstructionData(instruction_count, updPriceInstructionSize)); err != ErrUnknownInstruction {
+		t.Fatalf("expected ErrUnknownInstruction, got %v", err)
+	}
+}
+
+func TestParseInstruction(t *testing.T) {
+	funding, target, other := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	accounts := []solana.PublicKey{funding, target, other}
+
+	data := newInstructionData(Instruction_AddPrice, initPriceInstructionSize)
+	binary.LittleEndian.PutUint32(data[8:12], uint32(0xfffffff8)) // -8
+	binary.LittleEndian.PutUint32(data[12:16], uint32(PriceTypePrice))
+	instruction, err := ParseInstruction(accounts, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addPrice, ok := instruction.(*AddPriceInstruction)
+	if !ok || addPrice.Product != target || addPrice.NewPrice != other || addPrice.Exponent != -8 || addPrice.PriceType != PriceTypePrice {
+		t.Fatalf("unexpected add price %+v", instruction)
+	}
+
+	data = newInstructionData(Instruction_DelPublisher, publisherInstructionSize)
+	copy(data[8:40], other[:])
+	instruction, err = ParseInstruction(accounts, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delPublisher, ok := instruction.(*DelPublisherInstruction); !ok || delPublisher.PriceAccount != target || delPublisher.Publisher != other {
+		t.Fatalf("unexpected del publisher %+v", instruction)
+	}
+
+	data = newInstructionData(Instruction_SetMinPub, setMinPubInstructionSize)
+	data[8] = 3
+	instruction, err = ParseInstruction(accounts, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setMinPub, ok := instruction.(*SetMinPubInstruction); !ok || setMinPub.MinPublishers != 3 {
+		t.Fatalf("unexpected set min pub %+v", instruction)
+	}
+
+	data = newInstructionData(Instruction_UpdProduct, instructionHeaderSize)
+	data = append(data, 6)
+	data = append(data, "symbol"...)
+	data = append(data, 14)
+	data = append(data, "Crypto.SOL/USD"...)
+	instruction, err = ParseInstruction(accounts, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updProduct, ok := instruction.(*UpdProductInstruction); !ok || updProduct.Attributes["symbol"] != "Crypto.SOL/USD" {
+		t.Fatalf("unexpected upd product %+v", instruction)
+	}
+
+	data = newInstructionData(Instruction_UpdTest, updTestInstructionSize)
+	binary.LittleEndian.PutUint32(data[8:12], 2)
+	data[16+1] = 0xff // -1
+	binary.LittleEndian.PutUint64(data[16+MaxPriceComponents+8:], 42)
+	instruction, err = ParseInstruction(accounts, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updTest, ok := instruction.(*UpdTestInstruction); !ok || len(updTest.Prices) != 2 || updTest.SlotDiffs[1] != -1 || updTest.Prices[1] != 42 {
+		t.Fatalf("unexpected upd test %+v", instruction)
+	}
+}
@@ -18,6 +18,9 @@ func TestParse(t *testing.T) {
 
 	t.Logf("%+v", acc)
 
-	price := ParsePriceData(acc.Value[0].Data.GetBinary())
+	price, err := ParsePriceData(acc.Value[0].Data.GetBinary())
+	if err != nil {
+		t.Fatal(err)
+	}
 	spew.Dump(price)
 }
@@ -0,0 +1,218 @@
+package pyth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	// ReceiverProgramAddress pyth solana receiver, owner of PriceUpdateV2 accounts
+	ReceiverProgramAddress = solana.MustPublicKeyFromBase58("rec5EKMGg6MxZYaMdyBfgwp4d5rB9T1VQH5pJv5LtFJ")
+	// PushOracleProgramAddress keeps one PriceUpdateV2 account per feed and shard updated through the receiver
+	PushOracleProgramAddress = solana.MustPublicKeyFromBase58("pythWSnswVUd12oZpeFP8e9CVaEqJg25g1Vtc2biRsT")
+)
+
+var (
+	PriceUpdateV2Discriminator    = [...]byte{0x22, 0xf1, 0x23, 0x63, 0x9d, 0x7e, 0xf4, 0xcd}
+	PostUpdateDiscriminator       = [...]byte{0x85, 0x5f, 0xcf, 0xaf, 0x0b, 0x4f, 0x76, 0x2c}
+	PostUpdateAtomicDiscriminator = [...]byte{0x31, 0xac, 0x54, 0xc0, 0xaf, 0xb4, 0x34, 0xea}
+	UpdatePriceFeedDiscriminator  = [...]byte{0x1c, 0x09, 0x5d, 0x96, 0x56, 0x99, 0xbc, 0x73}
+)
+
+var (
+	ErrInvalidDiscriminator     = errors.New("pyth: invalid discriminator")
+	ErrInvalidVerificationLevel = errors.New("pyth: invalid verification level")
+	ErrInvalidMessage           = errors.New("pyth: invalid price feed message")
+	ErrPartiallyVerified        = errors.New("pyth: price update is not fully verified")
+)
+
+const (
+	// priceFeedMessageType accumulator message type of PriceFeedMessage
+	priceFeedMessageType = 0
+	// priceFeedMessageSize feed id + price + conf + exponent + publish time + prev publish time + ema price + ema conf
+	priceFeedMessageSize = 32 + 8 + 8 + 4 + 8 + 8 + 8 + 8
+
+	// price account index in receiver post_update / post_update_atomic and push oracle update_price_feed
+	PostUpdatePriceAccountIndex      = 4
+	UpdatePriceFeedPriceAccountIndex = 5
+)
+
+type VerificationLevel struct {
+	// Full means all guardian signatures were verified, otherwise NumSignatures were
+	Full          bool
+	NumSignatures uint8
+}
+
+// PriceFeedMessage values are raw, real price is Price * 10^Exponent
+type PriceFeedMessage struct {
+	FeedId          [32]byte
+	Price           int64
+	Conf            uint64
+	Exponent        int32
+	PublishTime     int64
+	PrevPublishTime int64
+	EmaPrice        int64
+	EmaConf         uint64
+}
+
+func (m *PriceFeedMessage) PriceFloat64() float64 {
+	return float64(m.Price) * math.Pow10(int(m.Exponent))
+}
+
+func (m *PriceFeedMessage) ConfFloat64() float64 {
+	return float64(m.Conf) * math.Pow10(int(m.Exponent))
+}
+
+func (m *PriceFeedMessage) EmaPriceFloat64() float64 {
+	return float64(m.EmaPrice) * math.Pow10(int(m.Exponent))
+}
+
+func (m *PriceFeedMessage) EmaConfFloat64() float64 {
+	return float64(m.EmaConf) * math.Pow10(int(m.Exponent))
+}
+
+type PriceUpdateV2 struct {
+	WriteAuthority    solana.PublicKey
+	VerificationLevel VerificationLevel
+	PriceMessage      PriceFeedMessage
+	PostedSlot        uint64
+}
+
+func ParsePriceUpdateV2(data []byte) (*PriceUpdateV2, error) {
+	if len(data) < 8+32+1 {
+		return nil, ErrInvalidAccountSize
+	}
+	if !bytes.Equal(data[0:8], PriceUpdateV2Discriminator[:]) {
+		return nil, ErrInvalidDiscriminator
+	}
+
+	offset := 8
+	writeAuthority := solana.PublicKeyFromBytes(data[offset : offset+32])
+	offset += 32
+
+	var verificationLevel VerificationLevel
+	switch data[offset] {
+	case 0:
+		if len(data) < offset+2 {
+			return nil, ErrInvalidAccountSize
+		}
+		verificationLevel.NumSignatures = data[offset+1]
+		offset += 2
+	case 1:
+		verificationLevel.Full = true
+		offset += 1
+	default:
+		return nil, ErrInvalidVerificationLevel
+	}
+
+	if len(data) < offset+priceFeedMessageSize+8 {
+		return nil, ErrInvalidAccountSize
+	}
+	message := data[offset : offset+priceFeedMessageSize]
+	priceMessage := PriceFeedMessage{
+		Price:           int64(binary.LittleEndian.Uint64(message[32:40])),
+		Conf:            binary.LittleEndian.Uint64(message[40:48]),
+		Exponent:        int32(binary.LittleEndian.Uint32(message[48:52])),
+		PublishTime:     int64(binary.LittleEndian.Uint64(message[52:60])),
+		PrevPublishTime: int64(binary.LittleEndian.Uint64(message[60:68])),
+		EmaPrice:        int64(binary.LittleEndian.Uint64(message[68:76])),
+		EmaConf:         binary.LittleEndian.Uint64(message[76:84]),
+	}
+	copy(priceMessage.FeedId[:], message[0:32])
+	offset += priceFeedMessageSize
+
+	return &PriceUpdateV2{
+		WriteAuthority:    writeAuthority,
+		VerificationLevel: verificationLevel,
+		PriceMessage:      priceMessage,
+		PostedSlot:        binary.LittleEndian.Uint64(data[offset : offset+8]),
+	}, nil
+}
+
+// FindPushOraclePriceFeedAddress is the PriceUpdateV2 account push oracle keeps for the feed
+func FindPushOraclePriceFeedAddress(shardId uint16, feedId [32]byte) (solana.PublicKey, error) {
+	shard := make([]byte, 2)
+	binary.LittleEndian.PutUint16(shard, shardId)
+	address, _, err := solana.FindProgramAddress([][]byte{shard, feedId[:]}, PushOracleProgramAddress)
+	return address, err
+}
+
+// IsPostUpdateInstruction tells if the instruction posts a price update in the pull model,
+// it's the pull oracle equivalent of IsUpdatePriceInstruction
+func IsPostUpdateInstruction(programId solana.PublicKey, data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	switch programId {
+	case ReceiverProgramAddress:
+		return bytes.Equal(data[0:8], PostUpdateDiscriminator[:]) || bytes.Equal(data[0:8], PostUpdateAtomicDiscriminator[:])
+	case PushOracleProgramAddress:
+		return bytes.Equal(data[0:8], UpdatePriceFeedDiscriminator[:])
+	}
+	return false
+}
+
+// PostUpdatePriceAccountPosition returns position of the updated PriceUpdateV2 account in instruction accounts
+func PostUpdatePriceAccountPosition(programId solana.PublicKey) int {
+	if programId == PushOracleProgramAddress {
+		return UpdatePriceFeedPriceAccountIndex
+	}
+	return PostUpdatePriceAccountIndex
+}
+
+// ParsePostUpdateInstruction decodes price feed message carried by a post update instruction,
+// the merkle proof is not verified
+func ParsePostUpdateInstruction(data []byte) (*PriceFeedMessage, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidMessage
+	}
+	params := data[8:]
+	if bytes.Equal(data[0:8], PostUpdateAtomicDiscriminator[:]) {
+		// vaa comes before the merkle price update
+		vaa, rest, ok := readVec(params)
+		if !ok || len(vaa) == 0 {
+			return nil, ErrInvalidMessage
+		}
+		params = rest
+	}
+	message, _, ok := readVec(params)
+	if !ok {
+		return nil, ErrInvalidMessage
+	}
+	return parsePriceFeedMessage(message)
+}
+
+// parsePriceFeedMessage decodes big endian accumulator message
+func parsePriceFeedMessage(message []byte) (*PriceFeedMessage, error) {
+	if len(message) < 1+priceFeedMessageSize || message[0] != priceFeedMessageType {
+		return nil, ErrInvalidMessage
+	}
+	message = message[1:]
+	priceMessage := &PriceFeedMessage{
+		Price:           int64(binary.BigEndian.Uint64(message[32:40])),
+		Conf:            binary.BigEndian.Uint64(message[40:48]),
+		Exponent:        int32(binary.BigEndian.Uint32(message[48:52])),
+		PublishTime:     int64(binary.BigEndian.Uint64(message[52:60])),
+		PrevPublishTime: int64(binary.BigEndian.Uint64(message[60:68])),
+		EmaPrice:        int64(binary.BigEndian.Uint64(message[68:76])),
+		EmaConf:         binary.BigEndian.Uint64(message[76:84]),
+	}
+	copy(priceMessage.FeedId[:], message[0:32])
+	return priceMessage, nil
+}
+
+// readVec reads borsh Vec<u8>
+func readVec(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	n := int(binary.LittleEndian.Uint32(data[0:4]))
+	if len(data) < 4+n {
+		return nil, nil, false
+	}
+	return data[4 : 4+n], data[4+n:], true
+}
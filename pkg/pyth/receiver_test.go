@@ -0,0 +1,118 @@
+package pyth
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestParsePriceUpdateV2(t *testing.T) {
+	writeAuthority := solana.NewWallet().PublicKey()
+	data := make([]byte, 0, 134)
+	data = append(data, PriceUpdateV2Discriminator[:]...)
+	data = append(data, writeAuthority[:]...)
+	data = append(data, 1) // full
+	feedId := [32]byte{0xef, 0x0d}
+	data = append(data, feedId[:]...)
+	data = binary.LittleEndian.AppendUint64(data, uint64(15012345678))
+	data = binary.LittleEndian.AppendUint64(data, 1234567)
+	data = binary.LittleEndian.AppendUint32(data, uint32(0xfffffff8)) // -8
+	data = binary.LittleEndian.AppendUint64(data, 1_700_000_000)
+	data = binary.LittleEndian.AppendUint64(data, 1_699_999_999)
+	data = binary.LittleEndian.AppendUint64(data, uint64(15000000000))
+	data = binary.LittleEndian.AppendUint64(data, 1000000)
+	data = binary.LittleEndian.AppendUint64(data, 280_000_000)
+
+	update, err := ParsePriceUpdateV2(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.WriteAuthority != writeAuthority || !update.VerificationLevel.Full || update.PostedSlot != 280_000_000 {
+		t.Fatalf("unexpected update %+v", update)
+	}
+	message := update.PriceMessage
+	if message.FeedId != feedId || message.Exponent != -8 || message.PublishTime != 1_700_000_000 || message.PrevPublishTime != 1_699_999_999 {
+		t.Fatalf("unexpected message %+v", message)
+	}
+	if math.Abs(message.PriceFloat64()-150.12345678) > 1e-9 || math.Abs(message.EmaPriceFloat64()-150) > 1e-9 || math.Abs(message.ConfFloat64()-0.01234567) > 1e-12 {
+		t.Fatalf("unexpected prices %+v", message)
+	}
+
+	// partial verification carries number of signatures
+	partial := append(append(append([]byte{}, data[:40]...), 0, 5), data[41:]...)
+	update, err = ParsePriceUpdateV2(partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.VerificationLevel.Full || update.VerificationLevel.NumSignatures != 5 || update.PostedSlot != 280_000_000 {
+		t.Fatalf("unexpected partial update %+v", update)
+	}
+
+	if _, err := ParsePriceUpdateV2(data[:len(data)-1]); err != ErrInvalidAccountSize {
+		t.Fatalf("expected ErrInvalidAccountSize, got %v", err)
+	}
+	invalidLevel := append([]byte{}, data...)
+	invalidLevel[40] = 2
+	if _, err := ParsePriceUpdateV2(invalidLevel); err != ErrInvalidVerificationLevel {
+		t.Fatalf("expected ErrInvalidVerificationLevel, got %v", err)
+	}
+	invalidDiscriminator := append([]byte{}, data...)
+	invalidDiscriminator[0] ^= 0xff
+	if _, err := ParsePriceUpdateV2(invalidDiscriminator); err != ErrInvalidDiscriminator {
+		t.Fatalf("expected ErrInvalidDiscriminator, got %v", err)
+	}
+}
+
+func TestParsePostUpdateInstruction(t *testing.T) {
+	message := []byte{priceFeedMessageType}
+	feedId := [32]byte{1, 2, 3}
+	message = append(message, feedId[:]...)
+	message = binary.BigEndian.AppendUint64(message, uint64(6_500_000))
+	message = binary.BigEndian.AppendUint64(message, 1_000)
+	message = binary.BigEndian.AppendUint32(message, uint32(0xfffffffb)) // -5
+	message = binary.BigEndian.AppendUint64(message, 1_700_000_000)
+	message = binary.BigEndian.AppendUint64(message, 1_699_999_999)
+	message = binary.BigEndian.AppendUint64(message, uint64(6_400_000))
+	message = binary.BigEndian.AppendUint64(message, 900)
+
+	merkleUpdate := binary.LittleEndian.AppendUint32(nil, uint32(len(message)))
+	merkleUpdate = append(merkleUpdate, message...)
+	merkleUpdate = binary.LittleEndian.AppendUint32(merkleUpdate, 0) // empty proof
+	merkleUpdate = append(merkleUpdate, 0)                           // treasury id
+
+	postUpdate := append(PostUpdateDiscriminator[:], merkleUpdate...)
+	vaa := []byte{1, 2, 3, 4}
+	postUpdateAtomic := append(PostUpdateAtomicDiscriminator[:], binary.LittleEndian.AppendUint32(nil, uint32(len(vaa)))...)
+	postUpdateAtomic = append(append(postUpdateAtomic, vaa...), merkleUpdate...)
+	updatePriceFeed := append(UpdatePriceFeedDiscriminator[:], merkleUpdate...)
+
+	cases := []struct {
+		program solana.PublicKey
+		data    []byte
+	}{
+		{ReceiverProgramAddress, postUpdate},
+		{ReceiverProgramAddress, postUpdateAtomic},
+		{PushOracleProgramAddress, updatePriceFeed},
+	}
+	for _, c := range cases {
+		if !IsPostUpdateInstruction(c.program, c.data) {
+			t.Fatalf("expected post update instruction %x", c.data[:8])
+		}
+		priceMessage, err := ParsePostUpdateInstruction(c.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if priceMessage.FeedId != feedId || math.Abs(priceMessage.PriceFloat64()-65) > 1e-9 || priceMessage.Exponent != -5 || priceMessage.EmaConf != 900 {
+			t.Fatalf("unexpected message %+v", priceMessage)
+		}
+	}
+
+	if IsPostUpdateInstruction(ProgramAddress, postUpdate) || IsPostUpdateInstruction(ReceiverProgramAddress, updatePriceFeed) {
+		t.Fatal("unexpected post update detection")
+	}
+	if _, err := ParsePostUpdateInstruction(postUpdate[:20]); err != ErrInvalidMessage {
+		t.Fatalf("expected ErrInvalidMessage, got %v", err)
+	}
+}
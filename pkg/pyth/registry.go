@@ -0,0 +1,113 @@
+package pyth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MappingAddress is the root mapping account of pyth on solana mainnet
+var MappingAddress = solana.MustPublicKeyFromBase58("AHtgzX45WTKfkPG53L6WYhGEXwQkN1BVknET3sVsLL8J")
+
+var ErrSymbolNotFound = errors.New("pyth: symbol not found")
+
+// maxMultipleAccounts getMultipleAccounts limit
+const maxMultipleAccounts = 100
+
+type Product struct {
+	Address      solana.PublicKey
+	PriceAccount solana.PublicKey
+	Attributes   map[string]string
+}
+
+// SymbolRegistry maps product symbols like "Crypto.SOL/USD" to their price accounts
+type SymbolRegistry struct {
+	bySymbol       map[string]*Product
+	byPriceAccount map[solana.PublicKey]*Product
+}
+
+func NewSymbolRegistry(products []*Product) *SymbolRegistry {
+	registry := &SymbolRegistry{
+		bySymbol:       make(map[string]*Product, len(products)),
+		byPriceAccount: make(map[solana.PublicKey]*Product, len(products)),
+	}
+	for _, product := range products {
+		if symbol := product.Attributes["symbol"]; symbol != "" {
+			registry.bySymbol[strings.ToUpper(symbol)] = product
+		}
+		if !product.PriceAccount.IsZero() {
+			registry.byPriceAccount[product.PriceAccount] = product
+		}
+	}
+	return registry
+}
+
+// LoadSymbolRegistry walks the mapping list starting at mappingAccount and decodes every product
+func LoadSymbolRegistry(connection *rpc.Client, mappingAccount solana.PublicKey) (*SymbolRegistry, error) {
+	productKeys := make([]solana.PublicKey, 0)
+	visited := make(map[solana.PublicKey]bool)
+	for next := mappingAccount; !next.IsZero() && !visited[next]; {
+		visited[next] = true
+		res, err := connection.GetAccountInfo(context.Background(), next)
+		if err != nil {
+			return nil, err
+		}
+		mapping, err := ParseMappingData(res.Value.Data.GetBinary())
+		if err != nil {
+			return nil, err
+		}
+		productKeys = append(productKeys, mapping.ProductAccounts...)
+		next = mapping.NextMappingAccount
+	}
+
+	products := make([]*Product, 0, len(productKeys))
+	for start := 0; start < len(productKeys); start += maxMultipleAccounts {
+		end := min(start+maxMultipleAccounts, len(productKeys))
+		res, err := connection.GetMultipleAccounts(context.Background(), productKeys[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for i, productRaw := range res.Value {
+			if productRaw == nil {
+				continue
+			}
+			product, err := ParseProductData(productRaw.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+			products = append(products, &Product{
+				Address:      productKeys[start+i],
+				PriceAccount: product.PriceAccount,
+				Attributes:   product.Attributes,
+			})
+		}
+	}
+	return NewSymbolRegistry(products), nil
+}
+
+// PriceAccount returns price account of the symbol, lookup is case insensitive
+func (r *SymbolRegistry) PriceAccount(symbol string) (solana.PublicKey, error) {
+	product, ok := r.bySymbol[strings.ToUpper(symbol)]
+	if !ok || product.PriceAccount.IsZero() {
+		return solana.PublicKey{}, ErrSymbolNotFound
+	}
+	return product.PriceAccount, nil
+}
+
+func (r *SymbolRegistry) Product(symbol string) (*Product, bool) {
+	product, ok := r.bySymbol[strings.ToUpper(symbol)]
+	return product, ok
+}
+
+// ProductByPriceAccount reverse lookup, e.g. to label oracles seen in the mempool
+func (r *SymbolRegistry) ProductByPriceAccount(priceAccount solana.PublicKey) (*Product, bool) {
+	product, ok := r.byPriceAccount[priceAccount]
+	return product, ok
+}
+
+func (r *SymbolRegistry) Len() int {
+	return len(r.bySymbol)
+}
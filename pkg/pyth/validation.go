@@ -0,0 +1,75 @@
+package pyth
+
+import "fmt"
+
+// ValidationOptions zero MaxSlotAge or MaxConfRatio disables the check
+type ValidationOptions struct {
+	// MaxSlotAge max difference between current slot and aggregate publish slot
+	MaxSlotAge uint64
+	// MaxConfRatio max conf / price
+	MaxConfRatio float64
+}
+
+var DefaultValidationOptions = ValidationOptions{
+	MaxSlotAge:   MaxSlotDifference,
+	MaxConfRatio: 0.05,
+}
+
+type PriceStatusError struct {
+	Status PriceStatus
+}
+
+func (e *PriceStatusError) Error() string {
+	return fmt.Sprintf("pyth: price status %d is not trading", e.Status)
+}
+
+type StalePriceError struct {
+	PublishSlot uint64
+	CurrentSlot uint64
+	MaxSlotAge  uint64
+}
+
+func (e *StalePriceError) Error() string {
+	return fmt.Sprintf("pyth: price published at slot %d is older than %d slots at slot %d", e.PublishSlot, e.MaxSlotAge, e.CurrentSlot)
+}
+
+type ConfidenceError struct {
+	Price float64
+	Conf  float64
+	// MaxConfRatio zero when price itself is not positive
+	MaxConfRatio float64
+}
+
+func (e *ConfidenceError) Error() string {
+	if e.Price <= 0 {
+		return fmt.Sprintf("pyth: price %v is not positive", e.Price)
+	}
+	return fmt.Sprintf("pyth: confidence %v is wider than %v of price %v", e.Conf, e.MaxConfRatio, e.Price)
+}
+
+// Validate checks aggregate price is trading, fresh at currentSlot and precise enough,
+// errors are *PriceStatusError, *StalePriceError or *ConfidenceError
+func (p *PriceData) Validate(currentSlot uint64, opts ValidationOptions) error {
+	return ValidatePriceInfo(p.Agg, currentSlot, opts)
+}
+
+func ValidatePriceInfo(info PriceInfo, currentSlot uint64, opts ValidationOptions) error {
+	if info.Status != PriceStatusTrading {
+		return &PriceStatusError{Status: info.Status}
+	}
+	if opts.MaxSlotAge > 0 && currentSlot > info.PublishSlot && currentSlot-info.PublishSlot > opts.MaxSlotAge {
+		return &StalePriceError{PublishSlot: info.PublishSlot, CurrentSlot: currentSlot, MaxSlotAge: opts.MaxSlotAge}
+	}
+	return ValidateConfidence(info.Price, info.Conf, opts.MaxConfRatio)
+}
+
+// ValidateConfidence checks price is positive and conf / price is within maxConfRatio
+func ValidateConfidence(price float64, conf float64, maxConfRatio float64) error {
+	if price <= 0 {
+		return &ConfidenceError{Price: price, Conf: conf}
+	}
+	if maxConfRatio > 0 && conf/price > maxConfRatio {
+		return &ConfidenceError{Price: price, Conf: conf, MaxConfRatio: maxConfRatio}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package pyth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePriceInfo(t *testing.T) {
+	valid := PriceInfo{Price: 100, Conf: 1, Status: PriceStatusTrading, PublishSlot: 1000}
+	if err := ValidatePriceInfo(valid, 1010, DefaultValidationOptions); err != nil {
+		t.Fatalf("expected valid price, got %v", err)
+	}
+
+	halted := valid
+	halted.Status = PriceStatusHalted
+	var statusErr *PriceStatusError
+	if err := ValidatePriceInfo(halted, 1000, DefaultValidationOptions); !errors.As(err, &statusErr) || statusErr.Status != PriceStatusHalted {
+		t.Fatalf("expected PriceStatusError, got %v", err)
+	}
+
+	var staleErr *StalePriceError
+	if err := ValidatePriceInfo(valid, 1000+MaxSlotDifference+1, DefaultValidationOptions); !errors.As(err, &staleErr) {
+		t.Fatalf("expected StalePriceError, got %v", err)
+	}
+	if err := ValidatePriceInfo(valid, 1000+MaxSlotDifference+1, ValidationOptions{}); err != nil {
+		t.Fatalf("disabled checks should pass, got %v", err)
+	}
+
+	wide := valid
+	wide.Conf = 6
+	var confErr *ConfidenceError
+	if err := ValidatePriceInfo(wide, 1000, DefaultValidationOptions); !errors.As(err, &confErr) {
+		t.Fatalf("expected ConfidenceError, got %v", err)
+	}
+	if err := ValidateConfidence(0, 0, 0); !errors.As(err, &confErr) {
+		t.Fatalf("expected ConfidenceError for zero price, got %v", err)
+	}
+}
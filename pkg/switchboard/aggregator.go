@@ -0,0 +1,116 @@
+package switchboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ProgramAddress = solana.MustPublicKeyFromBase58("SW1TCH7qEPTdLsDHRgPuMQjbQxKdH2aBStViMFnt64f")
+
+// AggregatorAccountDataDiscriminator sha256("account:AggregatorAccountData")[:8]
+var AggregatorAccountDataDiscriminator = [8]byte{0xd9, 0xe6, 0x41, 0x65, 0xc9, 0xa2, 0x1b, 0x7d}
+
+var (
+	ErrInvalidDiscriminator = errors.New("switchboard: invalid aggregator discriminator")
+	ErrInvalidAccountSize   = errors.New("switchboard: aggregator account data too small")
+	ErrInvalidRound         = errors.New("switchboard: latest round has not enough oracle results")
+)
+
+const (
+	// packed offsets, discriminator included
+	aggregatorMinOracleResultsOffset = 236
+	aggregatorLatestRoundOffset      = 341
+	aggregatorRoundSize              = 4 + 4 + 1 + 8 + 8 + decimalSize*4
+	decimalSize                      = 16 + 4
+
+	AggregatorMinSize = aggregatorLatestRoundOffset + aggregatorRoundSize
+)
+
+// Decimal is SwitchboardDecimal, value is Mantissa / 10^Scale
+type Decimal struct {
+	Mantissa *big.Int // i128
+	Scale    uint32
+}
+
+func ParseDecimal(data []byte) Decimal {
+	return Decimal{
+		Mantissa: parseInt128(data[0:16]),
+		Scale:    binary.LittleEndian.Uint32(data[16:20]),
+	}
+}
+
+func (d Decimal) Float64() float64 {
+	mantissa, _ := new(big.Float).SetInt(d.Mantissa).Float64()
+	return mantissa / math.Pow10(int(d.Scale))
+}
+
+type AggregatorRound struct {
+	NumSuccess         uint32
+	NumError           uint32
+	IsClosed           bool
+	RoundOpenSlot      uint64
+	RoundOpenTimestamp int64
+	Result             Decimal
+	StdDeviation       Decimal
+	MinResponse        Decimal
+	MaxResponse        Decimal
+	// oracles, medians and payouts are not decoded
+}
+
+func ParseAggregatorRound(data []byte) AggregatorRound {
+	return AggregatorRound{
+		NumSuccess:         binary.LittleEndian.Uint32(data[0:4]),
+		NumError:           binary.LittleEndian.Uint32(data[4:8]),
+		IsClosed:           data[8] == 1,
+		RoundOpenSlot:      binary.LittleEndian.Uint64(data[9:17]),
+		RoundOpenTimestamp: int64(binary.LittleEndian.Uint64(data[17:25])),
+		Result:             ParseDecimal(data[25:45]),
+		StdDeviation:       ParseDecimal(data[45:65]),
+		MinResponse:        ParseDecimal(data[65:85]),
+		MaxResponse:        ParseDecimal(data[85:105]),
+	}
+}
+
+type AggregatorAccountData struct {
+	MinOracleResults     uint32
+	LatestConfirmedRound AggregatorRound
+}
+
+func ParseAggregatorAccountData(data []byte) (*AggregatorAccountData, error) {
+	if len(data) < AggregatorMinSize {
+		return nil, ErrInvalidAccountSize
+	}
+	if !bytes.Equal(data[0:8], AggregatorAccountDataDiscriminator[:]) {
+		return nil, ErrInvalidDiscriminator
+	}
+
+	return &AggregatorAccountData{
+		MinOracleResults:     binary.LittleEndian.Uint32(data[aggregatorMinOracleResultsOffset : aggregatorMinOracleResultsOffset+4]),
+		LatestConfirmedRound: ParseAggregatorRound(data[aggregatorLatestRoundOffset:AggregatorMinSize]),
+	}, nil
+}
+
+// GetResult is latest confirmed result, it fails when the round was confirmed by fewer oracles than required
+func (a *AggregatorAccountData) GetResult() (float64, error) {
+	if a.LatestConfirmedRound.NumSuccess < a.MinOracleResults {
+		return 0, ErrInvalidRound
+	}
+	return a.LatestConfirmedRound.Result.Float64(), nil
+}
+
+func parseInt128(data []byte) *big.Int {
+	be := make([]byte, 16)
+	for i := range be {
+		be[i] = data[15-i]
+	}
+	value := new(big.Int).SetBytes(be)
+	if data[15]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package switchboard
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func putDecimal(data []byte, mantissa int64, scale uint32) {
+	binary.LittleEndian.PutUint64(data[0:8], uint64(mantissa))
+	fill := byte(0)
+	if mantissa < 0 {
+		fill = 0xff
+	}
+	for i := 8; i < 16; i++ {
+		data[i] = fill
+	}
+	binary.LittleEndian.PutUint32(data[16:20], scale)
+}
+
+func TestParseAggregatorAccountData(t *testing.T) {
+	data := make([]byte, 3851)
+	copy(data, AggregatorAccountDataDiscriminator[:])
+	binary.LittleEndian.PutUint32(data[aggregatorMinOracleResultsOffset:], 2)
+	round := data[aggregatorLatestRoundOffset:]
+	binary.LittleEndian.PutUint32(round[0:4], 3)
+	round[8] = 1
+	binary.LittleEndian.PutUint64(round[9:17], 250_000_000)
+	binary.LittleEndian.PutUint64(round[17:25], 1_700_000_000)
+	putDecimal(round[25:45], 123456789, 6)
+	putDecimal(round[45:65], 15, 3)
+	putDecimal(round[65:85], -5, 1)
+
+	aggregator, err := ParseAggregatorAccountData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := aggregator.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result-123.456789) > 1e-9 {
+		t.Fatalf("unexpected result %v", result)
+	}
+	if stdDev := aggregator.LatestConfirmedRound.StdDeviation.Float64(); math.Abs(stdDev-0.015) > 1e-12 {
+		t.Fatalf("unexpected std deviation %v", stdDev)
+	}
+	if minResponse := aggregator.LatestConfirmedRound.MinResponse.Float64(); minResponse != -0.5 {
+		t.Fatalf("unexpected min response %v", minResponse)
+	}
+	confirmed := aggregator.LatestConfirmedRound
+	if confirmed.RoundOpenSlot != 250_000_000 || confirmed.RoundOpenTimestamp != 1_700_000_000 || !confirmed.IsClosed {
+		t.Fatalf("unexpected round %+v", confirmed)
+	}
+
+	aggregator.MinOracleResults = 4
+	if _, err := aggregator.GetResult(); err != ErrInvalidRound {
+		t.Fatalf("expected ErrInvalidRound, got %v", err)
+	}
+
+	if _, err := ParseAggregatorAccountData(data[:AggregatorMinSize-1]); err != ErrInvalidAccountSize {
+		t.Fatalf("expected ErrInvalidAccountSize, got %v", err)
+	}
+	data[0] ^= 0xff
+	if _, err := ParseAggregatorAccountData(data); err != ErrInvalidDiscriminator {
+		t.Fatalf("expected ErrInvalidDiscriminator, got %v", err)
+	}
+}